@@ -1,23 +1,42 @@
 package app
 
 import (
+	"context"
 	"log"
 	"log/slog"
 
 	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	"github.com/jonx8/pr-review-service/internal/config"
 	"github.com/jonx8/pr-review-service/internal/database"
 	"github.com/jonx8/pr-review-service/internal/handlers"
+	"github.com/jonx8/pr-review-service/internal/metrics"
+	"github.com/jonx8/pr-review-service/internal/models"
 	"github.com/jonx8/pr-review-service/internal/repositories"
 	"github.com/jonx8/pr-review-service/internal/services"
+	"github.com/jonx8/pr-review-service/internal/tracing"
 )
 
 func RunApplication() error {
 	cfg := config.Load()
 
+	shutdownTracing, err := tracing.Init(context.Background(), *cfg.Tracing)
+	if err != nil {
+		slog.Error("Failed to initialize tracing", "error", err)
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Failed to shut down tracing", "error", err)
+		}
+	}()
+
 	db, err := database.InitDB(*cfg.DBConfig)
 	if err != nil {
 		slog.Error("Failed to connect to database:", "error", err)
@@ -41,12 +60,46 @@ func RunApplication() error {
 	teamRepo := repositories.NewTeamRepository(db)
 	userRepo := repositories.NewUserRepository(db)
 	prRepo := repositories.NewPRRepository(db)
+	forgeRepo := repositories.NewForgeRepository(db)
+	subscriptionRepo := repositories.NewSubscriptionRepository(db)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	statsRepo := repositories.NewStatsRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	absenceRepo := repositories.NewAbsenceRepository(db)
+
+	notificationService := services.NewNotificationService(subscriptionRepo, trManager)
+	outboxService := services.NewOutboxService(outboxRepo, notificationService, trManager)
+	userService := services.NewUserService(userRepo, outboxService, trManager)
+	teamService := services.NewTeamService(teamRepo, userService, trManager)
+	reviewerStrategies := services.NewReviewerStrategies(prRepo, teamRepo)
+	prService := services.NewPRService(prRepo, userService, teamService, reviewerStrategies, outboxService, trManager)
+
+	forgeService := services.NewForgeService(forgeRepo, prService, userService, trManager)
+	statsService := services.NewStatsService(statsRepo, teamService)
+	accessService := services.NewAccessService(prService, userService)
+	absenceService := services.NewAbsenceService(absenceRepo, userService, prService, trManager)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tokenVerifier, err := services.NewTokenVerifier(ctx, *cfg.Auth.JWT)
+	if err != nil {
+		slog.Error("Failed to initialize user token verification", "error", err)
+		return err
+	}
+	authService := services.NewAuthService(apiKeyRepo, userService, tokenVerifier, cfg.Auth.BootstrapKey)
+
+	prometheus.MustRegister(
+		collectors.NewDBStatsCollector(db.DB, "postgres"),
+		metrics.NewOpenPRsCollector(prRepo.CountOpenByTeam),
+	)
 
-	teamService := services.NewTeamService(teamRepo, trManager)
-	userService := services.NewUserService(userRepo, trManager)
-	prService := services.NewPRService(prRepo, userService, teamService, trManager)
+	go outboxService.RunDispatcher(ctx)
+	go notificationService.RunDispatcher(ctx)
+	go absenceService.RunScheduler(ctx)
+	go prService.RunReviewQueue(ctx)
 
-	router := SetupRouter(teamService, userService, prService)
+	router := SetupRouter(cfg, teamService, userService, prService, forgeService, notificationService, statsService, authService, accessService, absenceService)
 
 	log.Printf("Server starting on %s", cfg.ServerAddress)
 	log.Printf("Environment: %s", cfg.Environment)
@@ -59,37 +112,107 @@ func RunApplication() error {
 	return nil
 }
 
-func SetupRouter(teamService services.TeamService, userService services.UserService, prService services.PRService) *gin.Engine {
+func SetupRouter(cfg *config.Config, teamService services.TeamService, userService services.UserService, prService services.PRService, forgeService services.ForgeService, notificationService services.NotificationService, statsService services.StatsService, authService services.AuthService, accessService services.AccessService, absenceService services.AbsenceService) *gin.Engine {
 	router := gin.Default()
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	router.Use(handlers.MetricsMiddleware())
 
 	healthHandler := handlers.NewHealthHandler()
-	teamHandler := handlers.NewTeamHandler(teamService)
-	userHandler := handlers.NewUserHandler(userService, prService)
-	prHandler := handlers.NewPRHandler(prService)
+	teamHandler := handlers.NewTeamHandler(teamService, prService, accessService)
+	userHandler := handlers.NewUserHandler(userService, teamService, prService, accessService)
+	prHandler := handlers.NewPRHandler(prService, accessService)
+	webhookHandler := handlers.NewWebhookHandler(forgeService, cfg.Webhooks.GitHubSecret, cfg.Webhooks.GitLabToken)
+	subscriptionHandler := handlers.NewSubscriptionHandler(notificationService)
+	statsHandler := handlers.NewStatsHandler(statsService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(authService)
+	absenceHandler := handlers.NewAbsenceHandler(absenceService, accessService)
+
+	auth := handlers.AuthMiddleware(authService)
+	read := handlers.RequireScope(models.ScopeRead)
+	teamsWrite := handlers.RequireScope(models.ScopeTeamsWrite)
+	usersWrite := handlers.RequireScope(models.ScopeUsersWrite)
+	prsWrite := handlers.RequireScope(models.ScopePRsWrite)
+	admin := handlers.RequireScope(models.ScopeAdmin)
 
 	// Health routes
 	router.GET("/health", healthHandler.HealthCheck)
 
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	// Team routes
-	teamRoutes := router.Group("/team")
+	teamRoutes := router.Group("/team", auth)
 	{
-		teamRoutes.POST("/add", teamHandler.CreateTeam)
-		teamRoutes.GET("/get", teamHandler.GetTeam)
+		teamRoutes.POST("/add", teamsWrite, teamHandler.CreateTeam)
+		teamRoutes.GET("/get", read, teamHandler.GetTeam)
+		teamRoutes.POST("/deactivate", teamsWrite, teamHandler.DeactivateTeam)
+		teamRoutes.POST("/update", teamsWrite, teamHandler.UpdateTeam)
+		teamRoutes.POST("/addMember", teamsWrite, teamHandler.AddMember)
+		teamRoutes.POST("/removeMember", teamsWrite, teamHandler.RemoveMember)
+		teamRoutes.DELETE("", teamsWrite, teamHandler.DeleteTeam)
 	}
 
 	// User routes
-	userRoutes := router.Group("/users")
+	userRoutes := router.Group("/users", auth)
 	{
-		userRoutes.POST("/setIsActive", userHandler.SetUserActive)
-		userRoutes.GET("/getReview", userHandler.GetUserReviewPRs)
+		userRoutes.POST("/setIsActive", usersWrite, userHandler.SetUserActive)
+		userRoutes.POST("/setMaxOpenReviews", usersWrite, userHandler.SetMaxOpenReviews)
+		userRoutes.GET("/getReview", read, userHandler.GetUserReviewPRs)
+		userRoutes.POST("/moveTeam", usersWrite, userHandler.MoveTeam)
+		userRoutes.GET("/teamHistory", read, userHandler.GetTeamHistory)
+		userRoutes.POST("/absence", usersWrite, absenceHandler.CreateAbsence)
+		userRoutes.POST("/absence/import", usersWrite, absenceHandler.ImportAbsences)
+		userRoutes.DELETE("/absence", usersWrite, absenceHandler.DeleteAbsence)
+		userRoutes.GET("/absences", read, absenceHandler.GetAbsences)
 	}
 
 	// PR routes
-	prRoutes := router.Group("/pullRequest")
+	prRoutes := router.Group("/pullRequest", auth)
+	{
+		prRoutes.POST("/create", prsWrite, prHandler.CreatePR)
+		prRoutes.POST("/merge", prsWrite, prHandler.MergePR)
+		prRoutes.POST("/close", prsWrite, prHandler.ClosePR)
+		prRoutes.POST("/reopen", prsWrite, prHandler.ReopenPR)
+		prRoutes.POST("/ready", prsWrite, prHandler.MarkReady)
+		prRoutes.POST("/reassign", prsWrite, prHandler.ReassignReviewer)
+		prRoutes.POST("/review", prsWrite, prHandler.SubmitReview)
+		prRoutes.GET("/history", read, prHandler.GetHistory)
+	}
+
+	// Stats routes
+	statsRoutes := router.Group("/stats", auth, read)
+	{
+		statsRoutes.GET("/reviewers", statsHandler.GetReviewerStats)
+	}
+
+	// Forge webhook routes
+	webhookRoutes := router.Group("/webhooks")
+	{
+		webhookRoutes.POST("/github", webhookHandler.GitHub)
+		webhookRoutes.POST("/gitlab", webhookHandler.GitLab)
+	}
+
+	// Forge integration routes
+	integrationRoutes := router.Group("/integrations", auth, admin)
+	{
+		integrationRoutes.POST("/userMappings", webhookHandler.SetUserMapping)
+	}
+
+	// Outbound webhook subscription routes
+	subscriptionRoutes := router.Group("/subscriptions", auth, admin)
+	{
+		subscriptionRoutes.POST("", subscriptionHandler.CreateSubscription)
+		subscriptionRoutes.GET("", subscriptionHandler.GetSubscriptions)
+		subscriptionRoutes.POST("/deactivate", subscriptionHandler.DeactivateSubscription)
+		subscriptionRoutes.GET("/deliveries", subscriptionHandler.GetDeliveries)
+	}
+
+	// API key management routes
+	apiKeyRoutes := router.Group("/apiKeys", auth, admin)
 	{
-		prRoutes.POST("/create", prHandler.CreatePR)
-		prRoutes.POST("/merge", prHandler.MergePR)
-		prRoutes.POST("/reassign", prHandler.ReassignReviewer)
+		apiKeyRoutes.POST("", apiKeyHandler.CreateAPIKey)
+		apiKeyRoutes.GET("", apiKeyHandler.GetAPIKeys)
+		apiKeyRoutes.POST("/revoke", apiKeyHandler.RevokeAPIKey)
 	}
 
 	return router
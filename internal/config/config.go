@@ -11,6 +11,36 @@ type Config struct {
 	Environment   string
 	ServerAddress string
 	DBConfig      *DBConfig
+	Webhooks      *WebhooksConfig
+	Tracing       *TracingConfig
+	Auth          *AuthConfig
+}
+
+type AuthConfig struct {
+	BootstrapKey string
+	JWT          *JWTConfig
+}
+
+// JWTConfig describes how user tokens are verified. Tokens are accepted only
+// when a JWKS URL or file is configured.
+type JWTConfig struct {
+	JWKSURL   string
+	JWKSFile  string
+	Issuer    string
+	Audience  string
+	UserClaim string
+	RoleClaim string
+}
+
+type TracingConfig struct {
+	ServiceName  string
+	OTLPEndpoint string
+	Insecure     bool
+}
+
+type WebhooksConfig struct {
+	GitHubSecret string
+	GitLabToken  string
 }
 
 type DBConfig struct {
@@ -32,6 +62,9 @@ func Load() *Config {
 		Environment:   getEnv("ENVIRONMENT", "development"),
 		ServerAddress: getEnv("SERVER_ADDRESS", ":8080"),
 		DBConfig:      dbConfig,
+		Webhooks:      NewWebhooksConfig(),
+		Tracing:       NewTracingConfig(),
+		Auth:          NewAuthConfig(),
 	}
 }
 
@@ -49,6 +82,35 @@ func NewDBConfig() *DBConfig {
 	}
 }
 
+func NewWebhooksConfig() *WebhooksConfig {
+	return &WebhooksConfig{
+		GitHubSecret: getEnv("GITHUB_WEBHOOK_SECRET", ""),
+		GitLabToken:  getEnv("GITLAB_WEBHOOK_TOKEN", ""),
+	}
+}
+
+func NewTracingConfig() *TracingConfig {
+	return &TracingConfig{
+		ServiceName:  getEnv("OTEL_SERVICE_NAME", "pr-review-service"),
+		OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		Insecure:     getEnv("OTEL_EXPORTER_OTLP_INSECURE", "false") == "true",
+	}
+}
+
+func NewAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		BootstrapKey: getEnv("AUTH_BOOTSTRAP_KEY", ""),
+		JWT: &JWTConfig{
+			JWKSURL:   getEnv("AUTH_JWKS_URL", ""),
+			JWKSFile:  getEnv("AUTH_JWKS_FILE", ""),
+			Issuer:    getEnv("AUTH_JWT_ISSUER", ""),
+			Audience:  getEnv("AUTH_JWT_AUDIENCE", ""),
+			UserClaim: getEnv("AUTH_JWT_USER_CLAIM", "sub"),
+			RoleClaim: getEnv("AUTH_JWT_ROLE_CLAIM", "role"),
+		},
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
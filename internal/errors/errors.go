@@ -8,18 +8,30 @@ import (
 type ErrorType string
 
 const (
-	TypeBadRequest ErrorType = "BAD_REQUEST"
-	TypeNotFound   ErrorType = "NOT_FOUND"
-	TypeConflict   ErrorType = "CONFLICT"
-	TypeInternal   ErrorType = "INTERNAL"
+	TypeBadRequest   ErrorType = "BAD_REQUEST"
+	TypeNotFound     ErrorType = "NOT_FOUND"
+	TypeConflict     ErrorType = "CONFLICT"
+	TypeUnauthorized ErrorType = "UNAUTHORIZED"
+	TypeForbidden    ErrorType = "FORBIDDEN"
+	TypeInternal     ErrorType = "INTERNAL"
 )
 
 const (
 	CodeTeamExists    = "TEAM_EXISTS"
+	CodeTeamNotEmpty  = "TEAM_NOT_EMPTY"
+	CodeMemberExists  = "MEMBER_EXISTS"
 	CodePRExists      = "PR_EXISTS"
 	CodePRMerged      = "PR_MERGED"
 	CodeNotAssigned   = "NOT_ASSIGNED"
 	CodeNoCandidate   = "NO_CANDIDATE"
+	CodeNotEnough     = "NOT_ENOUGH_CANDIDATES"
+	CodeNoCapacity    = "NO_REVIEWER_CAPACITY"
+	CodeNotApproved   = "NOT_APPROVED"
+	CodePRNotOpen     = "PR_NOT_OPEN"
+	CodeInvalidStatus = "INVALID_STATUS_TRANSITION"
+	CodeInvalidSign   = "INVALID_SIGNATURE"
+	CodeUnauthorized  = "UNAUTHORIZED"
+	CodeForbidden     = "FORBIDDEN"
 	CodeNotFound      = "NOT_FOUND"
 	CodeBadRequest    = "BAD_REQUEST"
 	CodeInternalError = "INTERNAL_ERROR"
@@ -55,6 +67,26 @@ func NewTeamExists(message string) *AppError {
 	}
 }
 
+func NewTeamNotEmpty(message string) *AppError {
+	return &AppError{
+		Type:       TypeConflict,
+		Code:       CodeTeamNotEmpty,
+		Message:    message,
+		HTTPStatus: 409,
+		Stack:      debug.Stack(),
+	}
+}
+
+func NewMemberExists(message string) *AppError {
+	return &AppError{
+		Type:       TypeConflict,
+		Code:       CodeMemberExists,
+		Message:    message,
+		HTTPStatus: 409,
+		Stack:      debug.Stack(),
+	}
+}
+
 func NewPRExists(message string) *AppError {
 	return &AppError{
 		Type:       TypeConflict,
@@ -95,6 +127,56 @@ func NewNoCandidate(message string) *AppError {
 	}
 }
 
+func NewNotEnoughCandidates(message string) *AppError {
+	return &AppError{
+		Type:       TypeBadRequest,
+		Code:       CodeNotEnough,
+		Message:    message,
+		HTTPStatus: 409,
+		Stack:      debug.Stack(),
+	}
+}
+
+func NewNoCapacity(message string) *AppError {
+	return &AppError{
+		Type:       TypeBadRequest,
+		Code:       CodeNoCapacity,
+		Message:    message,
+		HTTPStatus: 409,
+		Stack:      debug.Stack(),
+	}
+}
+
+func NewNotApproved(message string) *AppError {
+	return &AppError{
+		Type:       TypeBadRequest,
+		Code:       CodeNotApproved,
+		Message:    message,
+		HTTPStatus: 409,
+		Stack:      debug.Stack(),
+	}
+}
+
+func NewPRNotOpen(message string) *AppError {
+	return &AppError{
+		Type:       TypeBadRequest,
+		Code:       CodePRNotOpen,
+		Message:    message,
+		HTTPStatus: 409,
+		Stack:      debug.Stack(),
+	}
+}
+
+func NewInvalidStatusTransition(message string) *AppError {
+	return &AppError{
+		Type:       TypeBadRequest,
+		Code:       CodeInvalidStatus,
+		Message:    message,
+		HTTPStatus: 409,
+		Stack:      debug.Stack(),
+	}
+}
+
 func NewNotFound(message string) *AppError {
 	return &AppError{
 		Type:       TypeNotFound,
@@ -115,6 +197,36 @@ func NewValidation(message string) *AppError {
 	}
 }
 
+func NewInvalidSignature(message string) *AppError {
+	return &AppError{
+		Type:       TypeUnauthorized,
+		Code:       CodeInvalidSign,
+		Message:    message,
+		HTTPStatus: 401,
+		Stack:      debug.Stack(),
+	}
+}
+
+func NewUnauthorized(message string) *AppError {
+	return &AppError{
+		Type:       TypeUnauthorized,
+		Code:       CodeUnauthorized,
+		Message:    message,
+		HTTPStatus: 401,
+		Stack:      debug.Stack(),
+	}
+}
+
+func NewForbidden(message string) *AppError {
+	return &AppError{
+		Type:       TypeForbidden,
+		Code:       CodeForbidden,
+		Message:    message,
+		HTTPStatus: 403,
+		Stack:      debug.Stack(),
+	}
+}
+
 func WrapInternal(err error, message string) *AppError {
 	return &AppError{
 		Type:       TypeInternal,
@@ -127,13 +239,30 @@ func WrapInternal(err error, message string) *AppError {
 }
 
 var (
-	ErrTeamExists     = NewTeamExists("team_name already exists")
-	ErrPRExists       = NewPRExists("PR id already exists")
-	ErrPRMerged       = NewPRMerged("cannot reassign on merged PR")
-	ErrNotAssigned    = NewNotAssigned("reviewer is not assigned to this PR")
-	ErrNoCandidate    = NewNoCandidate("no active replacement candidate in team")
-	ErrTeamNotFound   = NewNotFound("team not found")
-	ErrUserNotFound   = NewNotFound("user not found")
-	ErrPRNotFound     = NewNotFound("PR not found")
-	ErrAuthorNotFound = NewNotFound("author not found")
+	ErrTeamExists           = NewTeamExists("team_name already exists")
+	ErrTeamNotEmpty         = NewTeamNotEmpty("team still has members")
+	ErrAlreadyMember        = NewMemberExists("user is already a member of the team")
+	ErrPRExists             = NewPRExists("PR id already exists")
+	ErrPRMerged             = NewPRMerged("cannot reassign on merged PR")
+	ErrReviewMerged         = NewPRMerged("cannot review merged PR")
+	ErrNotAssigned          = NewNotAssigned("reviewer is not assigned to this PR")
+	ErrNoCandidate          = NewNoCandidate("no active replacement candidate in team")
+	ErrNotEnough            = NewNotEnoughCandidates("not enough active candidates for requested number of reviewers")
+	ErrNoCapacity           = NewNoCapacity("all candidates have reached their limit of open reviews")
+	ErrNotApproved          = NewNotApproved("PR does not have enough approvals to be merged")
+	ErrPRNotOpen            = NewPRNotOpen("PR is not open for review")
+	ErrTeamNotFound         = NewNotFound("team not found")
+	ErrUserNotFound         = NewNotFound("user not found")
+	ErrNotTeamMember        = NewNotFound("user is not a member of the team")
+	ErrUserHasNoTeam        = NewNotFound("user is not a member of any team")
+	ErrPRNotFound           = NewNotFound("PR not found")
+	ErrAuthorNotFound       = NewNotFound("author not found")
+	ErrSubscriptionNotFound = NewNotFound("subscription not found")
+	ErrInvalidSign          = NewInvalidSignature("webhook signature verification failed")
+	ErrInvalidToken         = NewInvalidSignature("webhook token verification failed")
+	ErrAPIKeyNotFound       = NewNotFound("API key not found")
+	ErrAbsenceNotFound      = NewNotFound("absence not found")
+	ErrUnauthorized         = NewUnauthorized("missing or invalid API key")
+	ErrInvalidUserToken     = NewUnauthorized("invalid user token")
+	ErrForbidden            = NewForbidden("caller is not allowed to perform this action")
 )
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/services"
+)
+
+const maxCalendarSize = 1 << 20
+
+type AbsenceHandler struct {
+	absenceService services.AbsenceService
+	accessService  services.AccessService
+}
+
+func NewAbsenceHandler(absenceService services.AbsenceService, accessService services.AccessService) *AbsenceHandler {
+	return &AbsenceHandler{
+		absenceService: absenceService,
+		accessService:  accessService,
+	}
+}
+
+func (h *AbsenceHandler) CreateAbsence(c *gin.Context) {
+	var req models.CreateAbsenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckUser(c.Request.Context(), req.UserID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	absence, err := h.absenceService.CreateAbsence(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, absence)
+}
+
+func (h *AbsenceHandler) GetAbsences(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		validationError(c, "user_id parameter is required")
+		return
+	}
+
+	absences, err := h.absenceService.GetAbsences(c.Request.Context(), userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":  userID,
+		"absences": absences,
+	})
+}
+
+func (h *AbsenceHandler) DeleteAbsence(c *gin.Context) {
+	absenceID, err := strconv.ParseInt(c.Query("absence_id"), 10, 64)
+	if err != nil {
+		validationError(c, "absence_id parameter is required and must be an integer")
+		return
+	}
+
+	absence, err := h.absenceService.GetAbsence(c.Request.Context(), absenceID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if err := h.accessService.CheckUser(c.Request.Context(), absence.UserID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if err := h.absenceService.DeleteAbsence(c.Request.Context(), absenceID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AbsenceHandler) ImportAbsences(c *gin.Context) {
+	var req models.ImportAbsencesRequest
+	if err := c.ShouldBind(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		validationError(c, "file is required")
+		return
+	}
+	if file.Size > maxCalendarSize {
+		validationError(c, "file must not exceed 1 MiB")
+		return
+	}
+
+	if err := h.accessService.CheckUser(c.Request.Context(), req.UserID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	calendar, err := file.Open()
+	if err != nil {
+		validationError(c, "failed to read file: "+err.Error())
+		return
+	}
+	defer calendar.Close()
+
+	report, err := h.absenceService.ImportAbsences(c.Request.Context(), req, calendar)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/services"
+)
+
+type APIKeyHandler struct {
+	authService services.AuthService
+}
+
+func NewAPIKeyHandler(authService services.AuthService) *APIKeyHandler {
+	return &APIKeyHandler{
+		authService: authService,
+	}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	key, err := h.authService.CreateAPIKey(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.authService.GetAPIKeys(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	var req models.RevokeAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	key, err := h.authService.RevokeAPIKey(c.Request.Context(), req.KeyID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
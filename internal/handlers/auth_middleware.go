@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	e "github.com/jonx8/pr-review-service/internal/errors"
+	"github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/services"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+)
+
+const principalContextKey = "principal"
+
+// AuthMiddleware rejects requests without a valid "Authorization: Bearer"
+// API key or user token. The caller is kept in the gin context for
+// RequireScope and in the request context for services, together with the
+// actor that changes are attributed to.
+func AuthMiddleware(authService services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			handleError(c, e.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		principal, err := authService.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			handleError(c, err)
+			c.Abort()
+			return
+		}
+
+		ctx := u.WithPrincipal(c.Request.Context(), principal)
+		if actorID := principal.ActorID(); actorID != nil {
+			ctx = u.WithActor(ctx, *actorID)
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Set(principalContextKey, principal)
+		c.Next()
+	}
+}
+
+// RequireScope allows the request only if the caller has scope.
+func RequireScope(scope models.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := c.MustGet(principalContextKey).(*models.Principal)
+		if !ok || !principal.HasScope(scope) {
+			handleError(c, e.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -9,12 +9,14 @@ import (
 )
 
 type PRHandler struct {
-	prService services.PRService
+	prService     services.PRService
+	accessService services.AccessService
 }
 
-func NewPRHandler(prService services.PRService) *PRHandler {
+func NewPRHandler(prService services.PRService, accessService services.AccessService) *PRHandler {
 	return &PRHandler{
-		prService: prService,
+		prService:     prService,
+		accessService: accessService,
 	}
 }
 
@@ -25,6 +27,11 @@ func (h *PRHandler) CreatePR(c *gin.Context) {
 		return
 	}
 
+	if err := h.accessService.CheckAuthor(c.Request.Context(), req.AuthorID); err != nil {
+		handleError(c, err)
+		return
+	}
+
 	pr, err := h.prService.CreatePR(c.Request.Context(), req)
 	if err != nil {
 		handleError(c, err)
@@ -41,7 +48,82 @@ func (h *PRHandler) MergePR(c *gin.Context) {
 		return
 	}
 
-	pr, err := h.prService.MergePR(c.Request.Context(), req.PullRequestID)
+	if err := h.accessService.CheckPR(c.Request.Context(), req.PullRequestID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if req.Force {
+		if err := h.accessService.CheckForceMerge(c.Request.Context()); err != nil {
+			handleError(c, err)
+			return
+		}
+	}
+
+	pr, err := h.prService.MergePR(c.Request.Context(), req.PullRequestID, req.Force)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pr)
+}
+
+func (h *PRHandler) ClosePR(c *gin.Context) {
+	var req models.ClosePRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckPR(c.Request.Context(), req.PullRequestID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	pr, err := h.prService.ClosePR(c.Request.Context(), req.PullRequestID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pr)
+}
+
+func (h *PRHandler) ReopenPR(c *gin.Context) {
+	var req models.ReopenPRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckPR(c.Request.Context(), req.PullRequestID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	pr, err := h.prService.ReopenPR(c.Request.Context(), req.PullRequestID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pr)
+}
+
+func (h *PRHandler) MarkReady(c *gin.Context) {
+	var req models.MarkReadyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckPR(c.Request.Context(), req.PullRequestID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	pr, err := h.prService.MarkReady(c.Request.Context(), req)
 	if err != nil {
 		handleError(c, err)
 		return
@@ -57,6 +139,11 @@ func (h *PRHandler) ReassignReviewer(c *gin.Context) {
 		return
 	}
 
+	if err := h.accessService.CheckReviewer(c.Request.Context(), req.PullRequestID, req.OldReviewerID); err != nil {
+		handleError(c, err)
+		return
+	}
+
 	pr, newRevieverId, err := h.prService.ReassignReviewer(c.Request.Context(), req.PullRequestID, req.OldReviewerID)
 	if err != nil {
 		handleError(c, err)
@@ -69,6 +156,27 @@ func (h *PRHandler) ReassignReviewer(c *gin.Context) {
 	})
 }
 
+func (h *PRHandler) SubmitReview(c *gin.Context) {
+	var req models.SubmitReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckReviewSubmitter(c.Request.Context(), req.ReviewerID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	pr, err := h.prService.SubmitReview(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pr)
+}
+
 func (h *PRHandler) GetUserReviewPRs(c *gin.Context) {
 	userID := c.Query("user_id")
 	if userID == "" {
@@ -87,3 +195,22 @@ func (h *PRHandler) GetUserReviewPRs(c *gin.Context) {
 		"pull_requests": prs,
 	})
 }
+
+func (h *PRHandler) GetHistory(c *gin.Context) {
+	prID := c.Query("pull_request_id")
+	if prID == "" {
+		validationError(c, "pull_request_id parameter is required")
+		return
+	}
+
+	events, err := h.prService.GetHistory(c.Request.Context(), prID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pull_request_id": prID,
+		"events":          events,
+	})
+}
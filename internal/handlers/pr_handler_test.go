@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/services"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+)
+
+func (s *fakePRService) SubmitReview(_ context.Context, request m.SubmitReviewRequest) (*m.PullRequest, error) {
+	s.record("SubmitReview %s reviewer=%s state=%s", request.PullRequestID, request.ReviewerID, request.State)
+	return s.get(request.PullRequestID)
+}
+
+func (s *fakePRService) ReassignReviewer(_ context.Context, prID string, oldReviewerID string) (*m.PullRequest, *string, error) {
+	s.record("ReassignReviewer %s old=%s", prID, oldReviewerID)
+	pr, err := s.get(prID)
+	return pr, nil, err
+}
+
+// newPRHandlerTest serves the PR routes on behalf of principal.
+func newPRHandlerTest(principal *m.Principal) (*gin.Engine, *fakePRService) {
+	gin.SetMode(gin.TestMode)
+
+	prService := &fakePRService{prs: map[string]*m.PullRequest{
+		"pr-1001": {
+			PullRequestID:     "pr-1001",
+			AuthorID:          "u1",
+			TeamName:          "backend",
+			Status:            m.StatusOpen,
+			AssignedReviewers: []string{"u2", "u3"},
+		},
+	}}
+	handler := NewPRHandler(prService, services.NewAccessService(prService, nil))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(u.WithPrincipal(c.Request.Context(), principal))
+	})
+	router.POST("/pullRequest/review", handler.SubmitReview)
+	router.POST("/pullRequest/reassign", handler.ReassignReviewer)
+
+	return router, prService
+}
+
+func postJSON(router *gin.Engine, path string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSubmitReviewOnlyAsSelf(t *testing.T) {
+	lead := &m.Principal{UserID: "u5", Teams: []string{"backend"}, Role: m.RoleTeamLead}
+	member := &m.Principal{UserID: "u2", Teams: []string{"backend"}, Role: m.RoleMember}
+	admin := &m.Principal{UserID: "u9", Role: m.RoleAdmin}
+
+	tests := []struct {
+		name       string
+		principal  *m.Principal
+		reviewerID string
+		wantStatus int
+	}{
+		{
+			name:       "reviewer submits their own review",
+			principal:  member,
+			reviewerID: "u2",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "team lead submits as another reviewer of their team",
+			principal:  lead,
+			reviewerID: "u2",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "member submits as another reviewer",
+			principal:  member,
+			reviewerID: "u3",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "admin is limited only by scopes",
+			principal:  admin,
+			reviewerID: "u2",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, prService := newPRHandlerTest(tt.principal)
+
+			w := postJSON(router, "/pullRequest/review", m.SubmitReviewRequest{
+				PullRequestID: "pr-1001",
+				ReviewerID:    tt.reviewerID,
+				State:         m.ReviewApproved,
+			})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			submitted := slices.ContainsFunc(prService.calls, func(call string) bool {
+				return call == "SubmitReview pr-1001 reviewer="+tt.reviewerID+" state=APPROVED"
+			})
+			if submitted != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("calls = %v, review submitted = %t", prService.calls, submitted)
+			}
+		})
+	}
+}
+
+func TestReassignReviewerByTeamLead(t *testing.T) {
+	lead := &m.Principal{UserID: "u5", Teams: []string{"backend"}, Role: m.RoleTeamLead}
+	router, prService := newPRHandlerTest(lead)
+
+	w := postJSON(router, "/pullRequest/reassign", m.ReassignReviewerRequest{
+		PullRequestID: "pr-1001",
+		OldReviewerID: "u2",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if !slices.Equal(prService.calls, []string{"ReassignReviewer pr-1001 old=u2"}) {
+		t.Errorf("calls = %v, want the lead's reassignment", prService.calls)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/services"
+)
+
+type StatsHandler struct {
+	statsService services.StatsService
+}
+
+func NewStatsHandler(statsService services.StatsService) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+	}
+}
+
+func (h *StatsHandler) GetReviewerStats(c *gin.Context) {
+	var filter models.ReviewerStatsFilter
+
+	if teamName := c.Query("team_name"); teamName != "" {
+		filter.TeamName = &teamName
+	}
+
+	var err error
+	if filter.From, err = timeQuery(c, "from"); err != nil {
+		validationError(c, "from parameter must be an RFC 3339 timestamp")
+		return
+	}
+	if filter.To, err = timeQuery(c, "to"); err != nil {
+		validationError(c, "to parameter must be an RFC 3339 timestamp")
+		return
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		validationError(c, "from must be earlier than to")
+		return
+	}
+
+	stats, err := h.statsService.GetReviewerStats(c.Request.Context(), filter)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviewers": stats,
+	})
+}
+
+func timeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/services"
+)
+
+type SubscriptionHandler struct {
+	notificationService services.NotificationService
+}
+
+func NewSubscriptionHandler(notificationService services.NotificationService) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		notificationService: notificationService,
+	}
+}
+
+func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
+	var req models.CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	subscription, err := h.notificationService.CreateSubscription(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	subscriptions, err := h.notificationService.GetSubscriptions(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
+
+func (h *SubscriptionHandler) DeactivateSubscription(c *gin.Context) {
+	var req models.DeactivateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	subscription, err := h.notificationService.DeactivateSubscription(c.Request.Context(), req.SubscriptionID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+func (h *SubscriptionHandler) GetDeliveries(c *gin.Context) {
+	subscriptionID, err := strconv.ParseInt(c.Query("subscription_id"), 10, 64)
+	if err != nil {
+		validationError(c, "subscription_id parameter must be an integer")
+		return
+	}
+
+	deliveries, err := h.notificationService.GetDeliveries(c.Request.Context(), subscriptionID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscription_id": subscriptionID,
+		"deliveries":      deliveries,
+	})
+}
@@ -9,12 +9,16 @@ import (
 )
 
 type TeamHandler struct {
-	teamService services.TeamService
+	teamService   services.TeamService
+	prService     services.PRService
+	accessService services.AccessService
 }
 
-func NewTeamHandler(teamService services.TeamService) *TeamHandler {
+func NewTeamHandler(teamService services.TeamService, prService services.PRService, accessService services.AccessService) *TeamHandler {
 	return &TeamHandler{
-		teamService: teamService,
+		teamService:   teamService,
+		prService:     prService,
+		accessService: accessService,
 	}
 }
 
@@ -34,7 +38,12 @@ func (h *TeamHandler) CreateTeam(c *gin.Context) {
 		userIDs[member.UserID] = true
 	}
 
-	createdTeam, err := h.teamService.CreateTeam(c.Request.Context(), &team)
+	if err := h.accessService.CheckTeam(c.Request.Context(), team.TeamName); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	createdTeam, err := h.prService.CreateTeam(c.Request.Context(), &team)
 	if err != nil {
 		handleError(c, err)
 		return
@@ -58,3 +67,107 @@ func (h *TeamHandler) GetTeam(c *gin.Context) {
 
 	c.JSON(http.StatusOK, team)
 }
+
+func (h *TeamHandler) DeactivateTeam(c *gin.Context) {
+	var req models.DeactivateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckTeam(c.Request.Context(), req.TeamName); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	report, err := h.prService.DeactivateTeam(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	var req models.UpdateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckTeam(c.Request.Context(), req.TeamName); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	team, err := h.teamService.UpdateTeam(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, team)
+}
+
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	teamName := c.Query("team_name")
+	if teamName == "" {
+		validationError(c, "team_name parameter is required")
+		return
+	}
+
+	if err := h.accessService.CheckTeam(c.Request.Context(), teamName); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if err := h.teamService.DeleteTeam(c.Request.Context(), teamName); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TeamHandler) AddMember(c *gin.Context) {
+	var req models.AddTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckTeam(c.Request.Context(), req.TeamName); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	team, err := h.teamService.AddMember(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, team)
+}
+
+func (h *TeamHandler) RemoveMember(c *gin.Context) {
+	var req models.RemoveTeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckTeam(c.Request.Context(), req.TeamName); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	report, err := h.prService.RemoveTeamMember(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -9,14 +9,18 @@ import (
 )
 
 type UserHandler struct {
-	userService services.UserService
-	prService   services.PRService
+	userService   services.UserService
+	teamService   services.TeamService
+	prService     services.PRService
+	accessService services.AccessService
 }
 
-func NewUserHandler(userService services.UserService, prService services.PRService) *UserHandler {
+func NewUserHandler(userService services.UserService, teamService services.TeamService, prService services.PRService, accessService services.AccessService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		prService:   prService,
+		userService:   userService,
+		teamService:   teamService,
+		prService:     prService,
+		accessService: accessService,
 	}
 }
 
@@ -27,6 +31,22 @@ func (h *UserHandler) SetUserActive(c *gin.Context) {
 		return
 	}
 
+	if err := h.accessService.CheckUser(c.Request.Context(), req.UserID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if req.ReassignReviews {
+		report, err := h.prService.SetIsActive(c.Request.Context(), req)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
 	user, err := h.userService.SetIsActive(c.Request.Context(), req)
 	if err != nil {
 		handleError(c, err)
@@ -36,6 +56,27 @@ func (h *UserHandler) SetUserActive(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+func (h *UserHandler) SetMaxOpenReviews(c *gin.Context) {
+	var req models.SetMaxOpenReviewsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckUser(c.Request.Context(), req.UserID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	user, err := h.userService.SetMaxOpenReviews(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
 func (h *UserHandler) GetUserReviewPRs(c *gin.Context) {
 	userID := c.Query("user_id")
 	if userID == "" {
@@ -51,3 +92,54 @@ func (h *UserHandler) GetUserReviewPRs(c *gin.Context) {
 
 	c.JSON(http.StatusOK, prs)
 }
+
+func (h *UserHandler) MoveTeam(c *gin.Context) {
+	var req models.MoveTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.accessService.CheckUser(c.Request.Context(), req.UserID); err != nil {
+		handleError(c, err)
+		return
+	}
+	if err := h.accessService.CheckTeam(c.Request.Context(), req.TeamName); err != nil {
+		handleError(c, err)
+		return
+	}
+	// Without from_team the user has at most one team, which CheckUser covers
+	if req.FromTeam != "" {
+		if err := h.accessService.CheckTeam(c.Request.Context(), req.FromTeam); err != nil {
+			handleError(c, err)
+			return
+		}
+	}
+
+	user, err := h.prService.MoveTeamMember(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) GetTeamHistory(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		validationError(c, "user_id parameter is required")
+		return
+	}
+
+	memberships, err := h.teamService.GetMembershipHistory(c.Request.Context(), userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":     userID,
+		"memberships": memberships,
+	})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	e "github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/services"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+)
+
+// fakeUserService serves users from a map.
+type fakeUserService struct {
+	services.UserService
+	users map[string]*m.User
+}
+
+func (s *fakeUserService) GetUser(_ context.Context, userID string) (*m.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, e.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *fakePRService) MoveTeamMember(_ context.Context, request m.MoveTeamRequest) (*m.User, error) {
+	s.record("MoveTeamMember %s from=%s to=%s", request.UserID, request.FromTeam, request.TeamName)
+	return &m.User{UserID: request.UserID, Teams: []string{request.TeamName}}, nil
+}
+
+func TestMoveTeamChecksBothTeams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The lead manages backend and payments; u2 belongs to search and backend
+	lead := &m.Principal{UserID: "u5", Teams: []string{"backend", "payments"}, Role: m.RoleTeamLead}
+	userService := &fakeUserService{users: map[string]*m.User{
+		"u2": {UserID: "u2", Teams: []string{"search", "backend"}},
+	}}
+
+	tests := []struct {
+		name       string
+		fromTeam   string
+		wantStatus int
+	}{
+		{
+			name:       "from a team of the lead",
+			fromTeam:   "backend",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "from a team the lead does not manage",
+			fromTeam:   "search",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prService := &fakePRService{}
+			handler := NewUserHandler(userService, nil, prService, services.NewAccessService(prService, userService))
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Request = c.Request.WithContext(u.WithPrincipal(c.Request.Context(), lead))
+			})
+			router.POST("/users/moveTeam", handler.MoveTeam)
+
+			w := postJSON(router, "/users/moveTeam", m.MoveTeamRequest{
+				UserID:   "u2",
+				FromTeam: tt.fromTeam,
+				TeamName: "payments",
+			})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			moved := slices.Contains(prService.calls, "MoveTeamMember u2 from="+tt.fromTeam+" to=payments")
+			if moved != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("calls = %v, user moved = %t", prService.calls, moved)
+			}
+		})
+	}
+}
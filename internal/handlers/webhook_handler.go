@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	e "github.com/jonx8/pr-review-service/internal/errors"
+	"github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/services"
+)
+
+type WebhookHandler struct {
+	forgeService services.ForgeService
+	githubSecret string
+	gitlabToken  string
+}
+
+func NewWebhookHandler(forgeService services.ForgeService, githubSecret string, gitlabToken string) *WebhookHandler {
+	return &WebhookHandler{
+		forgeService: forgeService,
+		githubSecret: githubSecret,
+		gitlabToken:  gitlabToken,
+	}
+}
+
+type gitHubPREvent struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		ID     int64  `json:"id"`
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Merged bool   `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+}
+
+func (h *WebhookHandler) GitHub(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		validationError(c, "Failed to read request body")
+		return
+	}
+
+	if !verifyGitHubSignature(h.githubSecret, c.GetHeader("X-Hub-Signature-256"), body) {
+		handleError(c, e.ErrInvalidSign)
+		return
+	}
+
+	switch c.GetHeader("X-GitHub-Event") {
+	case "ping":
+		c.JSON(http.StatusOK, gin.H{"status": "pong"})
+		return
+	case "pull_request":
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"status": "ignored"})
+		return
+	}
+
+	var payload gitHubPREvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		validationError(c, "Invalid pull_request payload: "+err.Error())
+		return
+	}
+
+	action, ok := gitHubPRAction(payload)
+	if !ok {
+		c.JSON(http.StatusAccepted, gin.H{"status": "ignored"})
+		return
+	}
+
+	pr, err := h.forgeService.HandlePREvent(c.Request.Context(), models.ForgePREvent{
+		Forge:       models.ForgeGitHub,
+		DeliveryID:  c.GetHeader("X-GitHub-Delivery"),
+		Action:      action,
+		ExternalID:  payload.PullRequest.ID,
+		Title:       payload.PullRequest.Title,
+		AuthorLogin: payload.PullRequest.User.Login,
+		Draft:       payload.PullRequest.Draft,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pr)
+}
+
+// gitLabMREvent is a merge request hook payload. Its user is whoever
+// triggered the event, so the author is taken from author_id instead.
+type gitLabMREvent struct {
+	ObjectAttributes struct {
+		ID       int64  `json:"id"`
+		AuthorID int64  `json:"author_id"`
+		Title    string `json:"title"`
+		Action   string `json:"action"`
+		Draft    bool   `json:"draft"`
+	} `json:"object_attributes"`
+}
+
+func (h *WebhookHandler) GitLab(c *gin.Context) {
+	token := c.GetHeader("X-Gitlab-Token")
+	if h.gitlabToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.gitlabToken)) != 1 {
+		handleError(c, e.ErrInvalidToken)
+		return
+	}
+
+	if c.GetHeader("X-Gitlab-Event") != "Merge Request Hook" {
+		c.JSON(http.StatusAccepted, gin.H{"status": "ignored"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		validationError(c, "Failed to read request body")
+		return
+	}
+
+	var payload gitLabMREvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		validationError(c, "Invalid merge request payload: "+err.Error())
+		return
+	}
+
+	action, ok := gitLabMRAction(payload.ObjectAttributes.Action)
+	if !ok {
+		c.JSON(http.StatusAccepted, gin.H{"status": "ignored"})
+		return
+	}
+
+	pr, err := h.forgeService.HandlePREvent(c.Request.Context(), models.ForgePREvent{
+		Forge:            models.ForgeGitLab,
+		DeliveryID:       gitLabDeliveryID(c.GetHeader("X-Gitlab-Event-UUID"), body),
+		Action:           action,
+		ExternalID:       payload.ObjectAttributes.ID,
+		Title:            payload.ObjectAttributes.Title,
+		AuthorExternalID: payload.ObjectAttributes.AuthorID,
+		Draft:            payload.ObjectAttributes.Draft,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pr)
+}
+
+func (h *WebhookHandler) SetUserMapping(c *gin.Context) {
+	var req models.ForgeUserMapping
+	if err := c.ShouldBindJSON(&req); err != nil {
+		validationError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	mapping, err := h.forgeService.SetUserMapping(c.Request.Context(), &req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+func gitHubPRAction(payload gitHubPREvent) (models.ForgePRAction, bool) {
+	switch payload.Action {
+	case "opened":
+		return models.ForgeActionOpened, true
+	case "edited":
+		return models.ForgeActionUpdated, true
+	case "ready_for_review":
+		return models.ForgeActionReady, true
+	case "reopened":
+		return models.ForgeActionReopened, true
+	case "closed":
+		if payload.PullRequest.Merged {
+			return models.ForgeActionMerged, true
+		}
+		return models.ForgeActionClosed, true
+	default:
+		return "", false
+	}
+}
+
+func gitLabMRAction(action string) (models.ForgePRAction, bool) {
+	switch action {
+	case "open":
+		return models.ForgeActionOpened, true
+	case "update":
+		return models.ForgeActionUpdated, true
+	case "reopen":
+		return models.ForgeActionReopened, true
+	case "merge":
+		return models.ForgeActionMerged, true
+	case "close":
+		return models.ForgeActionClosed, true
+	default:
+		return "", false
+	}
+}
+
+// gitLabDeliveryID returns the event UUID of the delivery. Older GitLab
+// versions do not send it, so retries of the same event are recognized by a
+// hash of the payload instead.
+func gitLabDeliveryID(eventUUID string, body []byte) string {
+	if eventUUID != "" {
+		return eventUUID
+	}
+
+	sum := sha256.Sum256(body)
+	return "payload-" + hex.EncodeToString(sum[:])
+}
+
+func verifyGitHubSignature(secret string, signature string, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(received, mac.Sum(nil))
+}
@@ -0,0 +1,546 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/avito-tech/go-transaction-manager/trm/v2"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/gin-gonic/gin"
+	e "github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+	"github.com/jonx8/pr-review-service/internal/services"
+)
+
+const (
+	testGitHubSecret = "github-webhook-secret"
+	testGitLabToken  = "gitlab-webhook-token"
+)
+
+// fakeTransaction stands in for a database transaction, so that the forge
+// service can run its transactional code without a database.
+type fakeTransaction struct {
+	closed chan struct{}
+}
+
+func newFakeTrManager() *manager.Manager {
+	return manager.Must(func(ctx context.Context, _ trm.Settings) (context.Context, trm.Transaction, error) {
+		return ctx, &fakeTransaction{closed: make(chan struct{})}, nil
+	})
+}
+
+func (t *fakeTransaction) Transaction() any { return t }
+
+func (t *fakeTransaction) Commit(context.Context) error {
+	close(t.closed)
+	return nil
+}
+
+func (t *fakeTransaction) Rollback(context.Context) error {
+	close(t.closed)
+	return nil
+}
+
+func (t *fakeTransaction) IsActive() bool {
+	select {
+	case <-t.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+func (t *fakeTransaction) Closed() <-chan struct{} { return t.closed }
+
+// fakeForgeRepo keeps user mappings and seen deliveries in memory. Users are
+// keyed by forge and login, external users by forge and forge user id.
+type fakeForgeRepo struct {
+	repo.ForgeRepository
+	users         map[string]string
+	externalUsers map[string]string
+	deliveries    map[string]bool
+}
+
+func (r *fakeForgeRepo) GetUserIDByLogin(_ context.Context, forge m.Forge, login string) (*string, error) {
+	userID, ok := r.users[string(forge)+"/"+login]
+	if !ok {
+		return nil, nil
+	}
+	return &userID, nil
+}
+
+func (r *fakeForgeRepo) GetUserIDByExternalID(_ context.Context, forge m.Forge, externalUserID int64) (*string, error) {
+	userID, ok := r.externalUsers[fmt.Sprintf("%s/%d", forge, externalUserID)]
+	if !ok {
+		return nil, nil
+	}
+	return &userID, nil
+}
+
+func (r *fakeForgeRepo) RegisterDelivery(_ context.Context, forge m.Forge, deliveryID string) (bool, error) {
+	key := string(forge) + "/" + deliveryID
+	if r.deliveries[key] {
+		return false, nil
+	}
+	r.deliveries[key] = true
+	return true, nil
+}
+
+// fakePRService records the PR operations the forge events are applied as.
+type fakePRService struct {
+	services.PRService
+	prs   map[string]*m.PullRequest
+	calls []string
+}
+
+func (s *fakePRService) record(call string, args ...any) {
+	s.calls = append(s.calls, fmt.Sprintf(call, args...))
+}
+
+func (s *fakePRService) get(prID string) (*m.PullRequest, error) {
+	pr, ok := s.prs[prID]
+	if !ok {
+		return nil, e.ErrPRNotFound
+	}
+	return pr, nil
+}
+
+func (s *fakePRService) GetPR(_ context.Context, prID string) (*m.PullRequest, error) {
+	return s.get(prID)
+}
+
+func (s *fakePRService) CreatePR(_ context.Context, request m.CreatePRRequest) (*m.PullRequest, error) {
+	s.record("CreatePR %s %q author=%s draft=%t", request.PullRequestID, request.PullRequestName, request.AuthorID, request.Draft)
+	if _, ok := s.prs[request.PullRequestID]; ok {
+		return nil, e.ErrPRExists
+	}
+	status := m.StatusOpen
+	if request.Draft {
+		status = m.StatusDraft
+	}
+	pr := &m.PullRequest{
+		PullRequestID:   request.PullRequestID,
+		PullRequestName: request.PullRequestName,
+		AuthorID:        request.AuthorID,
+		Status:          status,
+	}
+	s.prs[pr.PullRequestID] = pr
+	return pr, nil
+}
+
+func (s *fakePRService) UpdateTitle(_ context.Context, prID string, title string) (*m.PullRequest, error) {
+	s.record("UpdateTitle %s %q", prID, title)
+	return s.setStatus(prID, "")
+}
+
+func (s *fakePRService) MarkReady(_ context.Context, request m.MarkReadyRequest) (*m.PullRequest, error) {
+	s.record("MarkReady %s", request.PullRequestID)
+	return s.setStatus(request.PullRequestID, m.StatusOpen)
+}
+
+func (s *fakePRService) MergePR(_ context.Context, prID string, force bool) (*m.PullRequest, error) {
+	s.record("MergePR %s force=%t", prID, force)
+	return s.setStatus(prID, m.StatusMerged)
+}
+
+func (s *fakePRService) ClosePR(_ context.Context, prID string) (*m.PullRequest, error) {
+	s.record("ClosePR %s", prID)
+	return s.setStatus(prID, m.StatusClosed)
+}
+
+func (s *fakePRService) ReopenPR(_ context.Context, prID string) (*m.PullRequest, error) {
+	s.record("ReopenPR %s", prID)
+	return s.setStatus(prID, m.StatusReopened)
+}
+
+func (s *fakePRService) setStatus(prID string, status m.PRStatus) (*m.PullRequest, error) {
+	pr, err := s.get(prID)
+	if err != nil {
+		return nil, err
+	}
+	if status != "" {
+		pr.Status = status
+	}
+	return pr, nil
+}
+
+type webhookTest struct {
+	router    *gin.Engine
+	prService *fakePRService
+}
+
+func newWebhookTest(t *testing.T, secret string) *webhookTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	forgeRepo := &fakeForgeRepo{
+		users: map[string]string{
+			"github/alice-dev":   "u1",
+			"gitlab/release-bot": "u9",
+		},
+		externalUsers: map[string]string{"gitlab/4012": "u1", "gitlab/4077": "u2"},
+		deliveries:    make(map[string]bool),
+	}
+	prService := &fakePRService{prs: make(map[string]*m.PullRequest)}
+	forgeService := services.NewForgeService(forgeRepo, prService, nil, newFakeTrManager())
+	handler := NewWebhookHandler(forgeService, secret, testGitLabToken)
+
+	router := gin.New()
+	router.POST("/webhooks/github", handler.GitHub)
+	router.POST("/webhooks/gitlab", handler.GitLab)
+
+	return &webhookTest{router: router, prService: prService}
+}
+
+// deliver posts a GitHub webhook; an empty signature header is omitted.
+func (wt *webhookTest) deliver(event string, deliveryID string, signature string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	if deliveryID != "" {
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+	}
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature-256", signature)
+	}
+
+	recorder := httptest.NewRecorder()
+	wt.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// deliverGitLab posts a GitLab merge request hook; an empty event UUID header
+// is omitted.
+func (wt *webhookTest) deliverGitLab(eventUUID string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/gitlab", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+	req.Header.Set("X-Gitlab-Token", testGitLabToken)
+	if eventUUID != "" {
+		req.Header.Set("X-Gitlab-Event-UUID", eventUUID)
+	}
+
+	recorder := httptest.NewRecorder()
+	wt.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func signGitHub(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func readGitHubFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	return readFixture(t, "github", name)
+}
+
+func readFixture(t *testing.T, forge string, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", forge, name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	return body
+}
+
+// TestGitHubWebhookReplaysFixtures replays recorded GitHub deliveries in the
+// order a PR goes through them and checks the PR operations they result in.
+func TestGitHubWebhookReplaysFixtures(t *testing.T) {
+	const prID = "github-2087654321"
+
+	deliveries := []struct {
+		fixture    string
+		event      string
+		wantStatus int
+		wantCalls  []string
+	}{
+		{
+			fixture:    "ping.json",
+			event:      "ping",
+			wantStatus: http.StatusOK,
+		},
+		{
+			fixture:    "pull_request_opened_draft.json",
+			event:      "pull_request",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{`CreatePR ` + prID + ` "Add search endpoint" author=u1 draft=true`},
+		},
+		{
+			fixture:    "pull_request_synchronize.json",
+			event:      "pull_request",
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			fixture:    "pull_request_ready_for_review.json",
+			event:      "pull_request",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{"MarkReady " + prID},
+		},
+		{
+			fixture:    "pull_request_edited.json",
+			event:      "pull_request",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{`UpdateTitle ` + prID + ` "Add search endpoint with paging"`},
+		},
+		{
+			fixture:    "pull_request_closed.json",
+			event:      "pull_request",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{"ClosePR " + prID},
+		},
+		{
+			fixture:    "pull_request_reopened.json",
+			event:      "pull_request",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{"ReopenPR " + prID},
+		},
+		{
+			fixture:    "pull_request_closed_merged.json",
+			event:      "pull_request",
+			wantStatus: http.StatusOK,
+			wantCalls:  []string{"MergePR " + prID + " force=true"},
+		},
+		{
+			fixture:    "pull_request_opened.json",
+			event:      "issues",
+			wantStatus: http.StatusAccepted,
+		},
+	}
+
+	wt := newWebhookTest(t, testGitHubSecret)
+	for i, delivery := range deliveries {
+		body := readGitHubFixture(t, delivery.fixture)
+		wt.prService.calls = nil
+
+		recorder := wt.deliver(delivery.event, fmt.Sprintf("delivery-%d", i), signGitHub(testGitHubSecret, body), body)
+		if recorder.Code != delivery.wantStatus {
+			t.Fatalf("%s (%s): status = %d, want %d, body %s",
+				delivery.fixture, delivery.event, recorder.Code, delivery.wantStatus, recorder.Body)
+		}
+		if !slices.Equal(wt.prService.calls, delivery.wantCalls) {
+			t.Errorf("%s (%s): calls = %q, want %q", delivery.fixture, delivery.event, wt.prService.calls, delivery.wantCalls)
+		}
+	}
+
+	if status := wt.prService.prs[prID].Status; status != m.StatusMerged {
+		t.Errorf("final PR status = %s, want %s", status, m.StatusMerged)
+	}
+}
+
+func TestGitHubWebhookOpenedByUnmappedLogin(t *testing.T) {
+	wt := newWebhookTest(t, testGitHubSecret)
+	body := bytes.ReplaceAll(readGitHubFixture(t, "pull_request_opened.json"), []byte(`"alice-dev"`), []byte(`"mallory"`))
+
+	recorder := wt.deliver("pull_request", "delivery-1", signGitHub(testGitHubSecret, body), body)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+	if len(wt.prService.calls) != 0 {
+		t.Errorf("calls = %q, want none", wt.prService.calls)
+	}
+}
+
+func TestGitHubWebhookSignature(t *testing.T) {
+	body := readGitHubFixture(t, "pull_request_opened.json")
+
+	tests := []struct {
+		name       string
+		signature  string
+		body       []byte
+		wantStatus int
+	}{
+		{
+			name:       "valid signature",
+			signature:  signGitHub(testGitHubSecret, body),
+			body:       body,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "signed with another secret",
+			signature:  signGitHub("another-secret", body),
+			body:       body,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "body changed after signing",
+			signature:  signGitHub(testGitHubSecret, body),
+			body:       bytes.Replace(body, []byte("Add search endpoint"), []byte("Drop all tables"), 1),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "signature without sha256 prefix",
+			signature:  signGitHub(testGitHubSecret, body)[len("sha256="):],
+			body:       body,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "signature is not hex",
+			signature:  "sha256=not-a-hex-digest",
+			body:       body,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing signature header",
+			body:       body,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wt := newWebhookTest(t, testGitHubSecret)
+
+			recorder := wt.deliver("pull_request", "delivery-1", tt.signature, tt.body)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantStatus != http.StatusOK && len(wt.prService.calls) != 0 {
+				t.Errorf("calls = %q, want none for a rejected delivery", wt.prService.calls)
+			}
+		})
+	}
+}
+
+func TestGitHubWebhookRejectsEveryDeliveryWithoutSecret(t *testing.T) {
+	wt := newWebhookTest(t, "")
+
+	body := readGitHubFixture(t, "pull_request_opened.json")
+	recorder := wt.deliver("pull_request", "delivery-1", signGitHub("", body), body)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestGitHubWebhookDeduplicatesDeliveries(t *testing.T) {
+	const prID = "github-2087654321"
+
+	wt := newWebhookTest(t, testGitHubSecret)
+	opened := readGitHubFixture(t, "pull_request_opened.json")
+	closed := readGitHubFixture(t, "pull_request_closed.json")
+
+	steps := []struct {
+		name       string
+		body       []byte
+		deliveryID string
+		wantCalls  []string
+	}{
+		{
+			name:       "first delivery is applied",
+			body:       opened,
+			deliveryID: "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+			wantCalls:  []string{`CreatePR ` + prID + ` "Add search endpoint" author=u1 draft=false`},
+		},
+		{
+			name:       "redelivery is skipped",
+			body:       opened,
+			deliveryID: "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+		},
+		{
+			name:       "new delivery is applied",
+			body:       closed,
+			deliveryID: "8a1f3b40-cc78-11e3-8f4e-4c9367dc0958",
+			wantCalls:  []string{"ClosePR " + prID},
+		},
+		{
+			name:       "redelivery of an older event is skipped",
+			body:       opened,
+			deliveryID: "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+		},
+	}
+
+	for _, step := range steps {
+		wt.prService.calls = nil
+
+		recorder := wt.deliver("pull_request", step.deliveryID, signGitHub(testGitHubSecret, step.body), step.body)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d, body %s", step.name, recorder.Code, http.StatusOK, recorder.Body)
+		}
+		if !slices.Equal(wt.prService.calls, step.wantCalls) {
+			t.Errorf("%s: calls = %q, want %q", step.name, wt.prService.calls, step.wantCalls)
+		}
+	}
+
+	// A skipped redelivery answers with the current PR, not the stale event
+	if status := wt.prService.prs[prID].Status; status != m.StatusClosed {
+		t.Errorf("PR status = %s, want %s", status, m.StatusClosed)
+	}
+}
+
+// TestGitLabWebhookResolvesAuthorByID checks that the author of a merge
+// request opened on their behalf is taken from author_id, not from the user
+// that triggered the event.
+func TestGitLabWebhookResolvesAuthorByID(t *testing.T) {
+	wt := newWebhookTest(t, testGitHubSecret)
+	body := readFixture(t, "gitlab", "merge_request_open.json")
+
+	recorder := wt.deliverGitLab("b5c1a1f0-5d3e-4c1e-9a65-0f1d2c3b4a59", body)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+
+	want := []string{`CreatePR gitlab-99051 "Add search endpoint" author=u1 draft=false`}
+	if !slices.Equal(wt.prService.calls, want) {
+		t.Errorf("calls = %q, want %q", wt.prService.calls, want)
+	}
+}
+
+func TestGitLabWebhookDeduplicatesDeliveries(t *testing.T) {
+	const prID = "gitlab-99051"
+
+	wt := newWebhookTest(t, testGitHubSecret)
+	opened := readFixture(t, "gitlab", "merge_request_open.json")
+	closed := readFixture(t, "gitlab", "merge_request_close.json")
+
+	steps := []struct {
+		name      string
+		eventUUID string
+		body      []byte
+		wantCalls []string
+	}{
+		{
+			name:      "first delivery is applied",
+			eventUUID: "b5c1a1f0-5d3e-4c1e-9a65-0f1d2c3b4a59",
+			body:      opened,
+			wantCalls: []string{`CreatePR ` + prID + ` "Add search endpoint" author=u1 draft=false`},
+		},
+		{
+			name:      "redelivery with the same event UUID is skipped",
+			eventUUID: "b5c1a1f0-5d3e-4c1e-9a65-0f1d2c3b4a59",
+			body:      opened,
+		},
+		{
+			name:      "delivery without event UUID is applied",
+			body:      closed,
+			wantCalls: []string{"ClosePR " + prID},
+		},
+		{
+			name: "redelivery without event UUID is skipped",
+			body: closed,
+		},
+	}
+
+	for _, step := range steps {
+		wt.prService.calls = nil
+
+		recorder := wt.deliverGitLab(step.eventUUID, step.body)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d, body %s", step.name, recorder.Code, http.StatusOK, recorder.Body)
+		}
+		if !slices.Equal(wt.prService.calls, step.wantCalls) {
+			t.Errorf("%s: calls = %q, want %q", step.name, wt.prService.calls, step.wantCalls)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package ical reads events from iCalendar (RFC 5545) files. Only the parts
+// needed to import absences are supported: the UID, SUMMARY, DTSTART, DTEND
+// and DURATION properties of VEVENT components.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout        = "20060102"
+	dateTimeLayout    = "20060102T150405"
+	utcDateTimeLayout = "20060102T150405Z"
+)
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// Event is a VEVENT. All-day events start and end at midnight UTC; the end is
+// exclusive, as in the file.
+type Event struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse returns the events of the calendar. Cancelled events are skipped.
+// Events without DTEND and DURATION last one day when they are all-day and
+// are rejected otherwise.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current []property
+	inEvent := false
+
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			inEvent = true
+			current = nil
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if !inEvent {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", i+1)
+			}
+			inEvent = false
+
+			event, cancelled, err := buildEvent(current)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			if !cancelled {
+				events = append(events, event)
+			}
+		case inEvent:
+			current = append(current, prop)
+		}
+	}
+
+	if inEvent {
+		return nil, fmt.Errorf("unterminated VEVENT")
+	}
+
+	return events, nil
+}
+
+// unfold joins content lines continued with a leading space or tab.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+func parseProperty(line string) (property, error) {
+	colon := indexOutsideQuotes(line, ':')
+	if colon < 0 {
+		return property{}, fmt.Errorf("malformed content line %q", line)
+	}
+
+	parts := splitOutsideQuotes(line[:colon], ';')
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string, len(parts)-1),
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+
+	return prop, nil
+}
+
+func buildEvent(props []property) (Event, bool, error) {
+	var event Event
+	var start, end *property
+	var duration string
+	allDay := false
+
+	for i := range props {
+		prop := &props[i]
+		switch prop.name {
+		case "UID":
+			event.UID = prop.value
+		case "SUMMARY":
+			event.Summary = unescapeText(prop.value)
+		case "STATUS":
+			if strings.EqualFold(prop.value, "CANCELLED") {
+				return Event{}, true, nil
+			}
+		case "DTSTART":
+			start = prop
+		case "DTEND":
+			end = prop
+		case "DURATION":
+			duration = prop.value
+		}
+	}
+
+	if start == nil {
+		return Event{}, false, fmt.Errorf("event %q has no DTSTART", event.UID)
+	}
+
+	var err error
+	event.Start, allDay, err = parseTime(*start)
+	if err != nil {
+		return Event{}, false, fmt.Errorf("event %q: DTSTART: %w", event.UID, err)
+	}
+
+	switch {
+	case end != nil:
+		event.End, _, err = parseTime(*end)
+		if err != nil {
+			return Event{}, false, fmt.Errorf("event %q: DTEND: %w", event.UID, err)
+		}
+	case duration != "":
+		event.End, err = addDuration(event.Start, duration)
+		if err != nil {
+			return Event{}, false, fmt.Errorf("event %q: DURATION: %w", event.UID, err)
+		}
+	case allDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		return Event{}, false, fmt.Errorf("event %q has neither DTEND nor DURATION", event.UID)
+	}
+
+	return event, false, nil
+}
+
+// parseTime parses a DATE or DATE-TIME value. Times with TZID are read in
+// that zone and floating times in UTC. The second result reports a DATE.
+func parseTime(prop property) (time.Time, bool, error) {
+	value := prop.value
+
+	if strings.EqualFold(prop.params["VALUE"], "DATE") || len(value) == len(dateLayout) {
+		t, err := time.Parse(dateLayout, value)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcDateTimeLayout, value)
+		return t, false, err
+	}
+
+	location := time.UTC
+	if tzid := prop.params["TZID"]; tzid != "" {
+		loaded, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+		location = loaded
+	}
+
+	t, err := time.ParseInLocation(dateTimeLayout, value, location)
+	return t, false, err
+}
+
+func addDuration(start time.Time, value string) (time.Time, error) {
+	match := durationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || value == "PT" {
+		return time.Time{}, fmt.Errorf("malformed duration %q", value)
+	}
+
+	number := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+
+	sign := 1
+	if match[1] == "-" {
+		sign = -1
+	}
+
+	days := sign * (number(match[2])*7 + number(match[3]))
+	clock := time.Duration(sign) * (time.Duration(number(match[4]))*time.Hour +
+		time.Duration(number(match[5]))*time.Minute +
+		time.Duration(number(match[6]))*time.Second)
+
+	return start.AddDate(0, 0, days).Add(clock), nil
+}
+
+func unescapeText(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+func indexOutsideQuotes(s string, sep byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	for {
+		i := indexOutsideQuotes(s, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
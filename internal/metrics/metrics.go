@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "pr_review"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	PRsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prs_created_total",
+		Help:      "Number of created pull requests by author team.",
+	}, []string{"team"})
+
+	PRsMerged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prs_merged_total",
+		Help:      "Number of merged pull requests, split by forced merges.",
+	}, []string{"forced"})
+
+	Reassignments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviewer_reassignments_total",
+		Help:      "Number of reviewer replacements by reason.",
+	}, []string{"reason"})
+
+	NoCandidateFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "no_candidate_failures_total",
+		Help:      "Number of reviewer replacements that found no candidate, by team.",
+	}, []string{"team"})
+
+	UnderAssignedPRs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "under_assigned_prs_total",
+		Help:      "Number of reviewer assignments that got fewer reviewers than the team requires, by team.",
+	}, []string{"team"})
+
+	CapacityExhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviewer_capacity_exhausted_total",
+		Help:      "Number of reviewer selections where every candidate was at capacity, by team and capacity policy.",
+	}, []string{"team", "policy"})
+)
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const openPRsQueryTimeout = 5 * time.Second
+
+// OpenPRsCounter returns the number of open pull requests per team.
+type OpenPRsCounter func(ctx context.Context) (map[string]int, error)
+
+type openPRsCollector struct {
+	count OpenPRsCounter
+	desc  *prometheus.Desc
+}
+
+// NewOpenPRsCollector reports open pull requests per team, queried on every
+// scrape so the gauge cannot drift from the database.
+func NewOpenPRsCollector(count OpenPRsCounter) prometheus.Collector {
+	return &openPRsCollector{
+		count: count,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "open_prs"),
+			"Number of open pull requests by author team.",
+			[]string{"team"}, nil,
+		),
+	}
+}
+
+func (c *openPRsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *openPRsCollector) Collect(ch chan<- prometheus.Metric) {
+	const method = "openPRsCollector.Collect"
+
+	ctx, cancel := context.WithTimeout(context.Background(), openPRsQueryTimeout)
+	defer cancel()
+
+	counts, err := c.count(ctx)
+	if err != nil {
+		slog.Error("failed to count open PRs",
+			"method", method,
+			"error", err,
+		)
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	for team, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), team)
+	}
+}
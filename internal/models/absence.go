@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Absence is a period when the user is unavailable for review. Absent users
+// are skipped by reviewer selection; with ReassignReviews set, their open
+// reviews are handed over when the absence begins.
+type Absence struct {
+	AbsenceID           int64      `json:"absence_id" db:"id"`
+	UserID              string     `json:"user_id" db:"user_id"`
+	StartsAt            time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt              time.Time  `json:"ends_at" db:"ends_at"`
+	Reason              *string    `json:"reason,omitempty" db:"reason"`
+	ReassignReviews     bool       `json:"reassign_reviews" db:"reassign_reviews"`
+	ReviewsReassignedAt *time.Time `json:"reviews_reassigned_at,omitempty" db:"reviews_reassigned_at"`
+	ExternalUID         *string    `json:"external_uid,omitempty" db:"external_uid"`
+	ActorID             *string    `json:"actor_id,omitempty" db:"actor_id"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsActiveAt reports whether the absence covers the moment t.
+func (a *Absence) IsActiveAt(t time.Time) bool {
+	return !t.Before(a.StartsAt) && t.Before(a.EndsAt)
+}
+
+type CreateAbsenceRequest struct {
+	UserID          string    `json:"user_id" binding:"required"`
+	StartsAt        time.Time `json:"starts_at" binding:"required"`
+	EndsAt          time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	Reason          *string   `json:"reason" binding:"omitempty,max=255"`
+	ReassignReviews bool      `json:"reassign_reviews"`
+}
+
+// ImportAbsencesRequest carries the form fields of an iCalendar upload.
+type ImportAbsencesRequest struct {
+	UserID          string `form:"user_id" binding:"required"`
+	ReassignReviews bool   `form:"reassign_reviews"`
+}
+
+// AbsenceImportReport lists the absences created or updated from the events
+// of an uploaded calendar. Events that already ended are skipped.
+type AbsenceImportReport struct {
+	UserID   string    `json:"user_id"`
+	Imported []Absence `json:"imported"`
+	Skipped  int       `json:"skipped"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type Scope string
+
+const (
+	ScopeRead       Scope = "read"
+	ScopeTeamsWrite Scope = "teams:write"
+	ScopeUsersWrite Scope = "users:write"
+	ScopePRsWrite   Scope = "prs:write"
+	ScopeAdmin      Scope = "admin"
+)
+
+type APIKey struct {
+	ID        int64      `json:"key_id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Prefix    string     `json:"prefix" db:"prefix"`
+	Scopes    []Scope    `json:"scopes" db:"-"`
+	OwnerID   *string    `json:"owner_id,omitempty" db:"owner_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// CreateAPIKeyRequest creates a key. Without OwnerID the key is owned by the
+// user creating it.
+type CreateAPIKeyRequest struct {
+	Name    string  `json:"name" binding:"required,max=255"`
+	Scopes  []Scope `json:"scopes" binding:"required,min=1,unique,dive,oneof=read teams:write users:write prs:write admin"`
+	OwnerID string  `json:"owner_id" binding:"omitempty,min=1,max=50"`
+}
+
+type RevokeAPIKeyRequest struct {
+	KeyID int64 `json:"key_id" binding:"required"`
+}
+
+// CreatedAPIKey carries the plain key, which is only shown once on creation.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventPRCreated          EventType = "pr.created"
+	EventReviewerReassigned EventType = "pr.reviewer_reassigned"
+	EventReviewerAssigned   EventType = "pr.reviewer_assigned"
+	EventPRMerged           EventType = "pr.merged"
+	EventPRClosed           EventType = "pr.closed"
+	EventPRReopened         EventType = "pr.reopened"
+	EventUserDeactivated    EventType = "user.deactivated"
+)
+
+type Event struct {
+	Type       EventType `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+func NewEvent(eventType EventType, data any) Event {
+	return Event{
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		Data:       data,
+	}
+}
+
+type ReviewerReassignedData struct {
+	PullRequest   *PullRequest `json:"pr"`
+	OldReviewerID string       `json:"old_reviewer_id"`
+	NewReviewerID string       `json:"new_reviewer_id"`
+}
+
+// ReviewerAssignedData describes a reviewer added to an already open PR, when
+// a queued review slot is filled.
+type ReviewerAssignedData struct {
+	PullRequest *PullRequest `json:"pr"`
+	ReviewerID  string       `json:"reviewer_id"`
+}
+
+// OutboxMessage is an event stored in the same transaction as the change that
+// produced it, until it is handed over to subscribers.
+type OutboxMessage struct {
+	ID          int64           `db:"id"`
+	EventType   EventType       `db:"event_type"`
+	Payload     json.RawMessage `db:"payload"`
+	OccurredAt  time.Time       `db:"occurred_at"`
+	PublishedAt *time.Time      `db:"published_at"`
+}
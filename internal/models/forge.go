@@ -0,0 +1,45 @@
+package models
+
+type Forge string
+
+const (
+	ForgeGitHub Forge = "github"
+	ForgeGitLab Forge = "gitlab"
+)
+
+type ForgePRAction string
+
+const (
+	ForgeActionOpened   ForgePRAction = "OPENED"
+	ForgeActionReady    ForgePRAction = "READY"
+	ForgeActionMerged   ForgePRAction = "MERGED"
+	ForgeActionClosed   ForgePRAction = "CLOSED"
+	ForgeActionReopened ForgePRAction = "REOPENED"
+	ForgeActionUpdated  ForgePRAction = "UPDATED"
+)
+
+// ForgePREvent is a pull request event received from an external forge,
+// already translated from the forge specific payload.
+type ForgePREvent struct {
+	Forge       Forge
+	DeliveryID  string
+	Action      ForgePRAction
+	ExternalID  int64
+	Title       string
+	AuthorLogin string
+	Draft       bool
+
+	// AuthorExternalID is the forge user id of the author, set by forges
+	// that do not send the author's login.
+	AuthorExternalID int64
+}
+
+type ForgeUserMapping struct {
+	Forge  Forge  `json:"forge" db:"forge" binding:"required,oneof=github gitlab"`
+	Login  string `json:"login" db:"login" binding:"required,min=1,max=100"`
+	UserID string `json:"user_id" db:"user_id" binding:"required,min=1,max=50"`
+
+	// ExternalUserID is the numeric user id in the forge, required to
+	// resolve GitLab merge request authors.
+	ExternalUserID *int64 `json:"external_user_id,omitempty" db:"external_user_id" binding:"omitempty,min=1"`
+}
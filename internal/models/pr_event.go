@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type PREventType string
+
+const (
+	PREventAssigned    PREventType = "ASSIGNED"
+	PREventReplaced    PREventType = "REPLACED"
+	PREventMergeForced PREventType = "MERGE_FORCED"
+	PREventClosed      PREventType = "CLOSED"
+	PREventReopened    PREventType = "REOPENED"
+)
+
+type AssignmentReason string
+
+const (
+	ReasonAuto         AssignmentReason = "AUTO"
+	ReasonManual       AssignmentReason = "MANUAL"
+	ReasonDeactivation AssignmentReason = "DEACTIVATION"
+	ReasonRemoval      AssignmentReason = "REMOVAL"
+	ReasonAbsence      AssignmentReason = "ABSENCE"
+)
+
+// PREvent is an entry of the append-only PR history: reviewer assignments and
+// changes of the PR status, such as a forced merge, which have no reviewer.
+type PREvent struct {
+	ID                 int64            `json:"event_id" db:"id"`
+	PullRequestID      string           `json:"-" db:"pr_id"`
+	EventType          PREventType      `json:"event_type" db:"event_type"`
+	ReviewerID         string           `json:"reviewer_id,omitempty" db:"reviewer_id"`
+	PreviousReviewerID *string          `json:"previous_reviewer_id,omitempty" db:"previous_reviewer_id"`
+	FallbackTeam       *string          `json:"fallback_team,omitempty" db:"fallback_team"`
+	ActorID            *string          `json:"actor_id,omitempty" db:"actor_id"`
+	Reason             AssignmentReason `json:"reason" db:"reason"`
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import "slices"
+
+type Role string
+
+const (
+	RoleMember   Role = "member"
+	RoleTeamLead Role = "team_lead"
+	RoleAdmin    Role = "admin"
+)
+
+// roleScopes are the route scopes granted to users by role. Whether a user
+// may act on a particular team, user or PR is decided by AccessService.
+var roleScopes = map[Role][]Scope{
+	RoleMember:   {ScopeRead, ScopePRsWrite},
+	RoleTeamLead: {ScopeRead, ScopePRsWrite, ScopeTeamsWrite, ScopeUsersWrite},
+	RoleAdmin:    {ScopeAdmin},
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleScopes[r]
+	return ok
+}
+
+// Principal is the authenticated caller: either an API key or a user
+// identified by a JWT.
+type Principal struct {
+	APIKey *APIKey
+	UserID string
+	Teams  []string
+	Role   Role
+}
+
+func NewKeyPrincipal(key *APIKey) *Principal {
+	return &Principal{APIKey: key}
+}
+
+func NewUserPrincipal(user *User, role Role) *Principal {
+	return &Principal{
+		UserID: user.UserID,
+		Teams:  user.Teams,
+		Role:   role,
+	}
+}
+
+// InTeam reports whether the calling user belongs to the team.
+func (p *Principal) InTeam(teamName string) bool {
+	return slices.Contains(p.Teams, teamName)
+}
+
+// IsUser reports whether the caller is a user rather than an API key.
+func (p *Principal) IsUser() bool {
+	return p.APIKey == nil
+}
+
+// ActorID returns the user that changes made by the caller are attributed
+// to: the calling user or the owner of the API key. It is nil for keys
+// without an owner.
+func (p *Principal) ActorID() *string {
+	if p.APIKey != nil {
+		return p.APIKey.OwnerID
+	}
+	return &p.UserID
+}
+
+// IsAdmin reports whether the caller is an admin user or an API key with the
+// admin scope.
+func (p *Principal) IsAdmin() bool {
+	return p.HasScope(ScopeAdmin)
+}
+
+// HasScope reports whether the caller may use routes guarded by scope. The
+// admin scope grants every other scope.
+func (p *Principal) HasScope(scope Scope) bool {
+	scopes := roleScopes[p.Role]
+	if p.APIKey != nil {
+		scopes = p.APIKey.Scopes
+	}
+	return slices.Contains(scopes, scope) || slices.Contains(scopes, ScopeAdmin)
+}
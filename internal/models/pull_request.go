@@ -5,18 +5,35 @@ import "time"
 type PRStatus string
 
 const (
-	StatusOpen   PRStatus = "OPEN"
-	StatusMerged PRStatus = "MERGED"
+	StatusDraft    PRStatus = "DRAFT"
+	StatusOpen     PRStatus = "OPEN"
+	StatusReopened PRStatus = "REOPENED"
+	StatusMerged   PRStatus = "MERGED"
+	StatusClosed   PRStatus = "CLOSED"
 )
 
+// IsOpen reports whether the PR is under review.
+func (s PRStatus) IsOpen() bool {
+	return s == StatusOpen || s == StatusReopened
+}
+
 type PullRequest struct {
-	PullRequestID     string     `json:"pull_request_id" db:"id"`
-	PullRequestName   string     `json:"pull_request_name" db:"title"`
-	AuthorID          string     `json:"author_id" db:"author_id"`
-	Status            PRStatus   `json:"status" db:"status"`
-	AssignedReviewers []string   `json:"assigned_reviewers"`
-	CreatedAt         *time.Time `json:"-" db:"created_at"`
-	MergedAt          *time.Time `json:"mergedAt,omitempty" db:"merged_at"`
+	PullRequestID     string            `json:"pull_request_id" db:"id"`
+	PullRequestName   string            `json:"pull_request_name" db:"title"`
+	AuthorID          string            `json:"author_id" db:"author_id"`
+	TeamName          string            `json:"team_name,omitempty" db:"team_name"`
+	Status            PRStatus          `json:"status" db:"status"`
+	AssignedReviewers []string          `json:"assigned_reviewers"`
+	FallbackReviewers map[string]string `json:"fallback_reviewers,omitempty"`
+	Reviews           []Review          `json:"reviews,omitempty"`
+	CreatedAt         *time.Time        `json:"-" db:"created_at"`
+	MergedAt          *time.Time        `json:"mergedAt,omitempty" db:"merged_at"`
+	MergeForced       bool              `json:"merge_forced,omitempty" db:"merge_forced"`
+	ClosedAt          *time.Time        `json:"closedAt,omitempty" db:"closed_at"`
+
+	// MissingReviewers is the number of reviewers the PR fell short of when
+	// reviewers were assigned. It is only set in the assignment response.
+	MissingReviewers int `json:"missing_reviewers,omitempty" db:"-"`
 }
 
 type PullRequestShort struct {
@@ -26,17 +43,52 @@ type PullRequestShort struct {
 	Status          PRStatus `json:"status" db:"status"`
 }
 
+// CreatePRRequest opens a PR. TeamName selects which of the author's teams
+// reviews it and defaults to the author's primary team.
 type CreatePRRequest struct {
-	PullRequestID   string `json:"pull_request_id" binding:"required,min=1,max=50"`
-	PullRequestName string `json:"pull_request_name" binding:"required,min=1,max=255"`
-	AuthorID        string `json:"author_id" binding:"required,min=1,max=50"`
+	PullRequestID     string `json:"pull_request_id" binding:"required,min=1,max=50"`
+	PullRequestName   string `json:"pull_request_name" binding:"required,min=1,max=255"`
+	AuthorID          string `json:"author_id" binding:"required,min=1,max=50"`
+	TeamName          string `json:"team_name,omitempty" binding:"omitempty,min=1,max=100"`
+	ReviewersRequired *int   `json:"reviewers_required,omitempty" binding:"omitempty,min=1,max=10"`
+	Draft             bool   `json:"draft"`
 }
 
 type MergePRRequest struct {
 	PullRequestID string `json:"pull_request_id" binding:"required"`
+	Force         bool   `json:"force"`
+}
+
+type ClosePRRequest struct {
+	PullRequestID string `json:"pull_request_id" binding:"required"`
+}
+
+type ReopenPRRequest struct {
+	PullRequestID string `json:"pull_request_id" binding:"required"`
+}
+
+type MarkReadyRequest struct {
+	PullRequestID     string `json:"pull_request_id" binding:"required"`
+	ReviewersRequired *int   `json:"reviewers_required,omitempty" binding:"omitempty,min=1,max=10"`
 }
 
 type ReassignReviewerRequest struct {
 	PullRequestID string `json:"pull_request_id" binding:"required"`
 	OldReviewerID string `json:"old_reviewer_id" binding:"required"`
 }
+
+// OpenAssignment is an assignment of a reviewer to an open PR together with
+// all reviewers currently assigned to it.
+type OpenAssignment struct {
+	PullRequestShort
+	TeamName   string
+	ReviewerID string
+	Reviewers  []string
+}
+
+type ReviewerReplacement struct {
+	PullRequestID string
+	OldReviewerID string
+	NewReviewerID string
+	FallbackTeam  *string
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+type ReviewState string
+
+const (
+	ReviewApproved         ReviewState = "APPROVED"
+	ReviewChangesRequested ReviewState = "CHANGES_REQUESTED"
+	ReviewCommented        ReviewState = "COMMENTED"
+)
+
+type Review struct {
+	PullRequestID string      `json:"-" db:"pr_id"`
+	ReviewerID    string      `json:"reviewer_id" db:"reviewer_id"`
+	State         ReviewState `json:"state" db:"state"`
+	Comment       *string     `json:"comment,omitempty" db:"comment"`
+	SubmittedAt   time.Time   `json:"submitted_at" db:"submitted_at"`
+}
+
+// CurrentReviews reduces reviews given in submission order to the current
+// review of each reviewer, ordered by reviewer. A comment does not withdraw a
+// decision, so it is the latest APPROVED or CHANGES_REQUESTED review, or the
+// latest comment of a reviewer who has not decided yet.
+func CurrentReviews(reviews []Review) []Review {
+	current := make(map[string]Review)
+	for _, review := range reviews {
+		previous, ok := current[review.ReviewerID]
+		if ok && review.State == ReviewCommented && previous.State != ReviewCommented {
+			continue
+		}
+		current[review.ReviewerID] = review
+	}
+
+	result := make([]Review, 0, len(current))
+	for _, review := range current {
+		result = append(result, review)
+	}
+	slices.SortFunc(result, func(a, b Review) int {
+		return cmp.Compare(a.ReviewerID, b.ReviewerID)
+	})
+	return result
+}
+
+type SubmitReviewRequest struct {
+	PullRequestID string      `json:"pull_request_id" binding:"required"`
+	ReviewerID    string      `json:"reviewer_id" binding:"required"`
+	State         ReviewState `json:"state" binding:"required,oneof=APPROVED CHANGES_REQUESTED COMMENTED"`
+	Comment       *string     `json:"comment,omitempty" binding:"omitempty,max=2000"`
+}
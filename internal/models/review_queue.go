@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// QueuedReview is a review slot left unfilled because every candidate was at
+// capacity. When ReplacesReviewerID is set, the slot takes over that
+// reviewer's review once filled.
+type QueuedReview struct {
+	ID                 int64            `json:"-" db:"id"`
+	PullRequestID      string           `json:"pull_request_id" db:"pr_id"`
+	ReplacesReviewerID *string          `json:"replaces_reviewer_id,omitempty" db:"replaces_reviewer_id"`
+	Reason             AssignmentReason `json:"reason" db:"reason"`
+	ActorID            *string          `json:"actor_id,omitempty" db:"actor_id"`
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+}
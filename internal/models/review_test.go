@@ -0,0 +1,71 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCurrentReviews(t *testing.T) {
+	submitted := time.Date(2025, 10, 24, 12, 0, 0, 0, time.UTC)
+	history := func(states ...ReviewState) []Review {
+		reviews := make([]Review, len(states))
+		for i, state := range states {
+			reviews[i] = Review{ReviewerID: "u2", State: state, SubmittedAt: submitted.Add(time.Duration(i) * time.Minute)}
+		}
+		return reviews
+	}
+
+	tests := []struct {
+		name    string
+		reviews []Review
+		want    ReviewState
+	}{
+		{
+			name:    "comment after an approval keeps the approval",
+			reviews: history(ReviewApproved, ReviewCommented),
+			want:    ReviewApproved,
+		},
+		{
+			name:    "requested changes replace an approval",
+			reviews: history(ReviewApproved, ReviewCommented, ReviewChangesRequested),
+			want:    ReviewChangesRequested,
+		},
+		{
+			name:    "new approval replaces requested changes",
+			reviews: history(ReviewChangesRequested, ReviewCommented, ReviewApproved),
+			want:    ReviewApproved,
+		},
+		{
+			name:    "comments only",
+			reviews: history(ReviewCommented, ReviewCommented),
+			want:    ReviewCommented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CurrentReviews(tt.reviews)
+			if len(got) != 1 || got[0].State != tt.want {
+				t.Errorf("CurrentReviews() = %v, want a single %s review", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrentReviewsKeepsEachReviewer(t *testing.T) {
+	reviews := []Review{
+		{ReviewerID: "u3", State: ReviewApproved},
+		{ReviewerID: "u2", State: ReviewApproved},
+		{ReviewerID: "u3", State: ReviewCommented},
+		{ReviewerID: "u2", State: ReviewChangesRequested},
+	}
+
+	var got []string
+	for _, review := range CurrentReviews(reviews) {
+		got = append(got, review.ReviewerID+" "+string(review.State))
+	}
+	if want := []string{"u2 CHANGES_REQUESTED", "u3 APPROVED"}; !slices.Equal(got, want) {
+		t.Errorf("CurrentReviews() = %v, want %v", got, want)
+	}
+}
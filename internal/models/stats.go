@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type ReviewerStatsFilter struct {
+	TeamName *string
+	From     *time.Time
+	To       *time.Time
+}
+
+type ReviewerStats struct {
+	UserID           string   `json:"user_id" db:"user_id"`
+	Username         string   `json:"username" db:"username"`
+	Teams            []string `json:"teams" db:"-"`
+	TotalAssignments int      `json:"total_assignments" db:"total_assignments"`
+	OpenAssignments  int      `json:"open_assignments" db:"open_assignments"`
+	MergedReviewed   int      `json:"merged_reviewed" db:"merged_reviewed"`
+	ReassignedAway   int      `json:"reassigned_away" db:"reassigned_away"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "PENDING"
+	DeliveryDelivered DeliveryStatus = "DELIVERED"
+	DeliveryFailed    DeliveryStatus = "FAILED"
+)
+
+type Subscription struct {
+	ID        int64       `json:"subscription_id" db:"id"`
+	URL       string      `json:"url" db:"url"`
+	Secret    string      `json:"-" db:"secret"`
+	Events    []EventType `json:"events" db:"-"`
+	IsActive  bool        `json:"is_active" db:"is_active"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+}
+
+type CreateSubscriptionRequest struct {
+	URL    string      `json:"url" binding:"required,url,max=2048"`
+	Secret string      `json:"secret" binding:"required,min=16,max=255"`
+	Events []EventType `json:"events" binding:"required,min=1,unique,dive,oneof=pr.created pr.reviewer_reassigned pr.reviewer_assigned pr.merged pr.closed pr.reopened user.deactivated"`
+}
+
+type DeactivateSubscriptionRequest struct {
+	SubscriptionID int64 `json:"subscription_id" binding:"required"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"delivery_id" db:"id"`
+	SubscriptionID int64           `json:"subscription_id" db:"subscription_id"`
+	EventType      EventType       `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         DeliveryStatus  `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	ResponseStatus *int            `json:"response_status,omitempty" db:"response_status"`
+	LastError      *string         `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// DueDelivery is a pending delivery claimed for sending together with the
+// target of its subscription.
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
@@ -1,12 +1,137 @@
 package models
 
+import "time"
+
+type SelectionStrategy string
+
+const (
+	StrategyRandom      SelectionStrategy = "RANDOM"
+	StrategyRoundRobin  SelectionStrategy = "ROUND_ROBIN"
+	StrategyLeastLoaded SelectionStrategy = "LEAST_LOADED"
+	StrategyWeighted    SelectionStrategy = "WEIGHTED"
+)
+
+// CapacityPolicy decides what happens to a review slot when every candidate
+// has reached their limit of open reviews.
+type CapacityPolicy string
+
+const (
+	CapacityOverAssign    CapacityPolicy = "OVER_ASSIGN"
+	CapacityLeaveUnfilled CapacityPolicy = "LEAVE_UNFILLED"
+	CapacityFail          CapacityPolicy = "FAIL"
+)
+
 type Team struct {
-	TeamName string       `json:"team_name" db:"name" binding:"required,min=1,max=100"`
-	Members  []TeamMember `json:"members" binding:"required,dive"`
+	TeamName          string            `json:"team_name" db:"name" binding:"required,min=1,max=100"`
+	SelectionStrategy SelectionStrategy `json:"selection_strategy" db:"selection_strategy" binding:"omitempty,oneof=RANDOM ROUND_ROBIN LEAST_LOADED WEIGHTED"`
+	ReviewersRequired int               `json:"reviewers_required" db:"reviewers_required" binding:"omitempty,min=1,max=10"`
+	ApprovalsRequired *int              `json:"approvals_required" db:"approvals_required" binding:"omitempty,min=0,max=10"`
+	MaxOpenReviews    *int              `json:"max_open_reviews,omitempty" db:"max_open_reviews" binding:"omitempty,min=1,max=100"`
+	CapacityPolicy    CapacityPolicy    `json:"capacity_policy" db:"capacity_policy" binding:"omitempty,oneof=OVER_ASSIGN LEAVE_UNFILLED FAIL"`
+	RotationCursor    *string           `json:"rotation_cursor,omitempty" db:"rotation_cursor"`
+	FallbackTeams     []string          `json:"fallback_teams,omitempty" binding:"omitempty,unique,dive,min=1,max=100"`
+	Members           []TeamMember      `json:"members" binding:"required,dive"`
+	MoveMembers       bool              `json:"move_members,omitempty"`
 }
 
+// TeamMember is a user in a team. Absent and MaxOpenReviews are read with the
+// team and are ignored in requests.
 type TeamMember struct {
-	UserID   string `json:"user_id" db:"id" binding:"required,min=1,max=50"`
-	Username string `json:"username" db:"name" binding:"required,min=1,max=100"`
-	IsActive bool   `json:"is_active" db:"is_active" binding:"required"`
+	UserID         string `json:"user_id" db:"id" binding:"required,min=1,max=50"`
+	Username       string `json:"username" db:"name" binding:"required,min=1,max=100"`
+	IsActive       bool   `json:"is_active" db:"is_active" binding:"required"`
+	Absent         bool   `json:"absent,omitempty" db:"absent"`
+	MaxOpenReviews *int   `json:"max_open_reviews,omitempty" db:"max_open_reviews"`
+}
+
+// IsAvailable reports whether the member can be picked as a reviewer.
+func (tm TeamMember) IsAvailable() bool {
+	return tm.IsActive && !tm.Absent
+}
+
+// ReviewLimit returns the number of open reviews the member may have: their
+// own limit or, without one, the team default. The second result is false
+// when neither is set.
+func (tm TeamMember) ReviewLimit(teamDefault *int) (int, bool) {
+	if tm.MaxOpenReviews != nil {
+		return *tm.MaxOpenReviews, true
+	}
+	if teamDefault != nil {
+		return *teamDefault, true
+	}
+	return 0, false
+}
+
+// UpdateTeamRequest changes the settings of a team. Omitted fields are left
+// unchanged; an empty fallback_teams list removes all fallback teams and a
+// zero max_open_reviews removes the team default.
+type UpdateTeamRequest struct {
+	TeamName          string             `json:"team_name" binding:"required"`
+	SelectionStrategy *SelectionStrategy `json:"selection_strategy" binding:"omitempty,oneof=RANDOM ROUND_ROBIN LEAST_LOADED WEIGHTED"`
+	ReviewersRequired *int               `json:"reviewers_required" binding:"omitempty,min=1,max=10"`
+	ApprovalsRequired *int               `json:"approvals_required" binding:"omitempty,min=0,max=10"`
+	MaxOpenReviews    *int               `json:"max_open_reviews" binding:"omitempty,min=0,max=100"`
+	CapacityPolicy    *CapacityPolicy    `json:"capacity_policy" binding:"omitempty,oneof=OVER_ASSIGN LEAVE_UNFILLED FAIL"`
+	FallbackTeams     []string           `json:"fallback_teams" binding:"omitempty,unique,dive,min=1,max=100"`
+}
+
+// AddTeamMemberRequest adds a user to a team. Username is required to create
+// a new user and is ignored for existing ones; IsActive changes an existing
+// user only when it is set.
+type AddTeamMemberRequest struct {
+	TeamName string `json:"team_name" binding:"required"`
+	UserID   string `json:"user_id" binding:"required,min=1,max=50"`
+	Username string `json:"username" binding:"omitempty,min=1,max=100"`
+	IsActive *bool  `json:"is_active"`
+}
+
+// MoveTeamRequest moves a user from FromTeam to TeamName. FromTeam is only
+// required for users that belong to several teams.
+type MoveTeamRequest struct {
+	UserID   string `json:"user_id" binding:"required"`
+	FromTeam string `json:"from_team,omitempty"`
+	TeamName string `json:"team_name" binding:"required"`
+}
+
+// TeamMembership is an entry of a user's team membership history. LeftAt is
+// nil for the current membership.
+type TeamMembership struct {
+	TeamName string     `json:"team_name" db:"team_name"`
+	JoinedAt time.Time  `json:"joined_at" db:"joined_at"`
+	LeftAt   *time.Time `json:"left_at,omitempty" db:"left_at"`
+	ActorID  *string    `json:"actor_id,omitempty" db:"actor_id"`
+}
+
+type RemoveTeamMemberRequest struct {
+	TeamName string `json:"team_name" binding:"required"`
+	UserID   string `json:"user_id" binding:"required"`
+}
+
+// TeamMemberRemovalReport describes what happened to the open reviews of a
+// member removed from a team. Queued lists the reviews whose replacement
+// waits in the review queue.
+type TeamMemberRemovalReport struct {
+	TeamName        string           `json:"team_name"`
+	UserID          string           `json:"user_id"`
+	ReassignedCount int              `json:"reassigned_count"`
+	NoCandidate     []UnfilledReview `json:"no_candidate"`
+	Queued          []UnfilledReview `json:"queued"`
+}
+
+type DeactivateTeamRequest struct {
+	TeamName string   `json:"team_name" binding:"required"`
+	UserIDs  []string `json:"user_ids,omitempty" binding:"omitempty,unique,dive,min=1,max=50"`
+}
+
+type UnfilledReview struct {
+	PullRequestID string `json:"pull_request_id"`
+	ReviewerID    string `json:"reviewer_id"`
+}
+
+type TeamDeactivationReport struct {
+	TeamName        string           `json:"team_name"`
+	Deactivated     []string         `json:"deactivated"`
+	ReassignedCount int              `json:"reassigned_count"`
+	NoCandidate     []UnfilledReview `json:"no_candidate"`
+	Queued          []UnfilledReview `json:"queued"`
 }
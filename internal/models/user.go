@@ -1,13 +1,55 @@
 package models
 
+import "slices"
+
+// User is a developer. Teams lists the teams the user belongs to in the order
+// they joined them; the first one is the user's primary team. MaxOpenReviews
+// overrides the teams' default limit of open reviews.
 type User struct {
-	UserID   string `json:"user_id" db:"id"`
-	Username string `json:"username" db:"name"`
-	TeamName string `json:"team_name" db:"team_name"`
-	IsActive bool   `json:"is_active" db:"is_active"`
+	UserID         string   `json:"user_id" db:"id"`
+	Username       string   `json:"username" db:"name"`
+	Teams          []string `json:"teams" db:"-"`
+	IsActive       bool     `json:"is_active" db:"is_active"`
+	MaxOpenReviews *int     `json:"max_open_reviews,omitempty" db:"max_open_reviews"`
+}
+
+// PrimaryTeam returns the team the user joined first, or an empty string for
+// a user without a team.
+func (u *User) PrimaryTeam() string {
+	if len(u.Teams) == 0 {
+		return ""
+	}
+	return u.Teams[0]
+}
+
+func (u *User) IsMemberOf(teamName string) bool {
+	return slices.Contains(u.Teams, teamName)
 }
 
 type SetActiveRequest struct {
-	UserID   string `json:"user_id" binding:"required"`
-	IsActive bool   `json:"is_active"`
+	UserID          string `json:"user_id" binding:"required"`
+	IsActive        bool   `json:"is_active"`
+	ReassignReviews bool   `json:"reassign_reviews"`
+}
+
+// SetMaxOpenReviewsRequest sets the user's limit of open reviews. A null
+// limit makes the user fall back to the default of their teams.
+type SetMaxOpenReviewsRequest struct {
+	UserID         string `json:"user_id" binding:"required"`
+	MaxOpenReviews *int   `json:"max_open_reviews" binding:"omitempty,min=1,max=100"`
+}
+
+type ReviewerReassignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	NewReviewerID string `json:"new_reviewer_id"`
+}
+
+// DeactivationReport describes what happened to the open reviews of a user
+// deactivated with reassign_reviews set. Queued lists the PRs whose
+// replacement waits in the review queue, while the user keeps the review.
+type DeactivationReport struct {
+	User        *User                  `json:"user"`
+	Reassigned  []ReviewerReassignment `json:"reassigned"`
+	NoCandidate []string               `json:"no_candidate"`
+	Queued      []string               `json:"queued"`
 }
@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/jmoiron/sqlx"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type AbsenceRepository interface {
+	Save(ctx context.Context, absence *m.Absence) error
+	GetByID(ctx context.Context, absenceID int64) (*m.Absence, error)
+	GetByUser(ctx context.Context, userID string) ([]m.Absence, error)
+	Delete(ctx context.Context, absenceID int64) error
+	GetStartingForUpdate(ctx context.Context, afterID int64, limit int) ([]m.Absence, error)
+	MarkReviewsReassigned(ctx context.Context, ids []int64) error
+}
+
+type absenceRepository struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewAbsenceRepository(db *sqlx.DB) AbsenceRepository {
+	return &absenceRepository{
+		db:     db,
+		getter: trmsqlx.DefaultCtxGetter,
+	}
+}
+
+const absenceColumns = `
+	id, user_id, starts_at, ends_at, reason, reassign_reviews,
+	reviews_reassigned_at, external_uid, actor_id, created_at
+`
+
+// Save creates the absence. An absence imported from a calendar replaces the
+// one previously imported for the same event.
+func (r *absenceRepository) Save(ctx context.Context, absence *m.Absence) error {
+	const method = "AbsenceRepository.Save"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("user_id", absence.UserID))
+	defer span.End()
+
+	query := `
+		INSERT INTO user_absences (user_id, starts_at, ends_at, reason, reassign_reviews, reviews_reassigned_at, external_uid, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, external_uid) WHERE external_uid IS NOT NULL
+		DO UPDATE SET
+			starts_at = EXCLUDED.starts_at,
+			ends_at = EXCLUDED.ends_at,
+			reason = EXCLUDED.reason,
+			reassign_reviews = EXCLUDED.reassign_reviews,
+			reviews_reassigned_at = COALESCE(user_absences.reviews_reassigned_at, EXCLUDED.reviews_reassigned_at),
+			actor_id = EXCLUDED.actor_id
+		RETURNING ` + absenceColumns
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, absence, query,
+		absence.UserID,
+		absence.StartsAt,
+		absence.EndsAt,
+		absence.Reason,
+		absence.ReassignReviews,
+		absence.ReviewsReassignedAt,
+		absence.ExternalUID,
+		absence.ActorID,
+	)
+	if err != nil {
+		slog.Error("failed to save absence",
+			"method", method,
+			"user_id", absence.UserID,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *absenceRepository) GetByID(ctx context.Context, absenceID int64) (*m.Absence, error) {
+	const method = "AbsenceRepository.GetByID"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.Int64("absence_id", absenceID))
+	defer span.End()
+
+	query := `SELECT ` + absenceColumns + ` FROM user_absences WHERE id = $1`
+	var absence m.Absence
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &absence, query, absenceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.Error("failed to get absence",
+			"method", method,
+			"absence_id", absenceID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return &absence, nil
+}
+
+func (r *absenceRepository) GetByUser(ctx context.Context, userID string) ([]m.Absence, error) {
+	const method = "AbsenceRepository.GetByUser"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
+	query := `
+		SELECT ` + absenceColumns + `
+		FROM user_absences
+		WHERE user_id = $1
+		ORDER BY starts_at, id
+	`
+	absences := []m.Absence{}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &absences, query, userID)
+	if err != nil {
+		slog.Error("failed to get absences",
+			"method", method,
+			"user_id", userID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return absences, nil
+}
+
+func (r *absenceRepository) Delete(ctx context.Context, absenceID int64) error {
+	const method = "AbsenceRepository.Delete"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.Int64("absence_id", absenceID))
+	defer span.End()
+
+	query := `DELETE FROM user_absences WHERE id = $1`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, absenceID)
+	if err != nil {
+		slog.Error("failed to delete absence",
+			"method", method,
+			"absence_id", absenceID,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// GetStartingForUpdate locks absences following afterID that have begun and
+// whose open reviews still have to be handed over. Rows locked by another
+// scheduler are skipped.
+func (r *absenceRepository) GetStartingForUpdate(ctx context.Context, afterID int64, limit int) ([]m.Absence, error) {
+	const method = "AbsenceRepository.GetStartingForUpdate"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		SELECT ` + absenceColumns + `
+		FROM user_absences
+		WHERE reassign_reviews
+			AND reviews_reassigned_at IS NULL
+			AND starts_at <= NOW()
+			AND ends_at > NOW()
+			AND id > $1
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	var absences []m.Absence
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &absences, query, afterID, limit)
+	if err != nil {
+		slog.Error("failed to get starting absences",
+			"method", method,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return absences, nil
+}
+
+func (r *absenceRepository) MarkReviewsReassigned(ctx context.Context, ids []int64) error {
+	const method = "AbsenceRepository.MarkReviewsReassigned"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `UPDATE user_absences SET reviews_reassigned_at = NOW() WHERE id = ANY($1)`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		slog.Error("failed to mark absence reviews reassigned",
+			"method", method,
+			"absences_count", len(ids),
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/jmoiron/sqlx"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *m.APIKey, keyHash string) error
+	GetByHash(ctx context.Context, keyHash string) (*m.APIKey, error)
+	GetAll(ctx context.Context) ([]m.APIKey, error)
+	Revoke(ctx context.Context, keyID int64) (*m.APIKey, error)
+}
+
+type apiKeyRepository struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) APIKeyRepository {
+	return &apiKeyRepository{
+		db:     db,
+		getter: trmsqlx.DefaultCtxGetter,
+	}
+}
+
+type apiKeyRow struct {
+	m.APIKey
+	Scopes pq.StringArray `db:"scopes"`
+}
+
+func (row apiKeyRow) toModel() m.APIKey {
+	key := row.APIKey
+	key.Scopes = make([]m.Scope, len(row.Scopes))
+	for i, scope := range row.Scopes {
+		key.Scopes[i] = m.Scope(scope)
+	}
+	return key
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *m.APIKey, keyHash string) error {
+	const method = "APIKeyRepository.Create"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	scopes := make(pq.StringArray, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, owner_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).QueryRowxContext(ctx, query,
+		key.Name,
+		key.Prefix,
+		keyHash,
+		scopes,
+		key.OwnerID,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		slog.Error("failed to create API key",
+			"method", method,
+			"name", key.Name,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*m.APIKey, error) {
+	const method = "APIKeyRepository.GetByHash"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		SELECT id, name, prefix, scopes, owner_id, created_at, revoked_at
+		FROM api_keys
+		WHERE key_hash = $1
+	`
+	var row apiKeyRow
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &row, query, keyHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.Error("failed to get API key",
+			"method", method,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	key := row.toModel()
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetAll(ctx context.Context) ([]m.APIKey, error) {
+	const method = "APIKeyRepository.GetAll"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		SELECT id, name, prefix, scopes, owner_id, created_at, revoked_at
+		FROM api_keys
+		ORDER BY id
+	`
+	var rows []apiKeyRow
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query)
+	if err != nil {
+		slog.Error("failed to get API keys",
+			"method", method,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	keys := make([]m.APIKey, len(rows))
+	for i, row := range rows {
+		keys[i] = row.toModel()
+	}
+
+	return keys, nil
+}
+
+// Revoke marks the key as revoked. Revoking an already revoked key keeps its
+// original revocation time. A nil key is returned when it does not exist.
+func (r *apiKeyRepository) Revoke(ctx context.Context, keyID int64) (*m.APIKey, error) {
+	const method = "APIKeyRepository.Revoke"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.Int64("key_id", keyID))
+	defer span.End()
+
+	query := `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING id, name, prefix, scopes, owner_id, created_at, revoked_at
+	`
+	var row apiKeyRow
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &row, query, keyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.Error("failed to revoke API key",
+			"method", method,
+			"key_id", keyID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	key := row.toModel()
+	return &key, nil
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/jmoiron/sqlx"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ForgeRepository interface {
+	GetUserIDByLogin(ctx context.Context, forge m.Forge, login string) (*string, error)
+	GetUserIDByExternalID(ctx context.Context, forge m.Forge, externalUserID int64) (*string, error)
+	UpsertUserMapping(ctx context.Context, mapping *m.ForgeUserMapping) error
+	RegisterDelivery(ctx context.Context, forge m.Forge, deliveryID string) (bool, error)
+}
+
+type forgeRepository struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewForgeRepository(db *sqlx.DB) ForgeRepository {
+	return &forgeRepository{
+		db:     db,
+		getter: trmsqlx.DefaultCtxGetter,
+	}
+}
+
+func (r *forgeRepository) GetUserIDByLogin(ctx context.Context, forge m.Forge, login string) (*string, error) {
+	const method = "ForgeRepository.GetUserIDByLogin"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("forge", string(forge)))
+	defer span.End()
+
+	query := `SELECT user_id FROM forge_user_mappings WHERE forge = $1 AND login = $2`
+	var userID string
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &userID, query, forge, login)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.Error("failed to get user mapping",
+			"method", method,
+			"forge", forge,
+			"login", login,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return &userID, nil
+}
+
+func (r *forgeRepository) GetUserIDByExternalID(ctx context.Context, forge m.Forge, externalUserID int64) (*string, error) {
+	const method = "ForgeRepository.GetUserIDByExternalID"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("forge", string(forge)))
+	defer span.End()
+
+	query := `SELECT user_id FROM forge_user_mappings WHERE forge = $1 AND external_user_id = $2`
+	var userID string
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &userID, query, forge, externalUserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.Error("failed to get user mapping",
+			"method", method,
+			"forge", forge,
+			"external_user_id", externalUserID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return &userID, nil
+}
+
+func (r *forgeRepository) UpsertUserMapping(ctx context.Context, mapping *m.ForgeUserMapping) error {
+	const method = "ForgeRepository.UpsertUserMapping"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		INSERT INTO forge_user_mappings (forge, login, user_id, external_user_id)
+		VALUES (:forge, :login, :user_id, :external_user_id)
+		ON CONFLICT (forge, login)
+		DO UPDATE SET user_id = EXCLUDED.user_id, external_user_id = EXCLUDED.external_user_id
+	`
+
+	_, err := sqlx.NamedExecContext(ctx, r.getter.DefaultTrOrDB(ctx, r.db), query, mapping)
+	if err != nil {
+		slog.Error("failed to upsert user mapping",
+			"method", method,
+			"forge", mapping.Forge,
+			"login", mapping.Login,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// RegisterDelivery records the delivery and reports whether it was seen for
+// the first time.
+func (r *forgeRepository) RegisterDelivery(ctx context.Context, forge m.Forge, deliveryID string) (bool, error) {
+	const method = "ForgeRepository.RegisterDelivery"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("forge", string(forge)), attribute.String("delivery_id", deliveryID))
+	defer span.End()
+
+	query := `
+		INSERT INTO forge_deliveries (forge, delivery_id)
+		VALUES ($1, $2)
+		ON CONFLICT (forge, delivery_id) DO NOTHING
+	`
+
+	result, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, forge, deliveryID)
+	if err != nil {
+		slog.Error("failed to register delivery",
+			"method", method,
+			"forge", forge,
+			"delivery_id", deliveryID,
+			"error", err,
+		)
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return inserted > 0, nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/jmoiron/sqlx"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"github.com/lib/pq"
+)
+
+type OutboxRepository interface {
+	Add(ctx context.Context, messages []m.OutboxMessage) error
+	GetPendingForUpdate(ctx context.Context, limit int) ([]m.OutboxMessage, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+}
+
+type outboxRepository struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewOutboxRepository(db *sqlx.DB) OutboxRepository {
+	return &outboxRepository{
+		db:     db,
+		getter: trmsqlx.DefaultCtxGetter,
+	}
+}
+
+func (r *outboxRepository) Add(ctx context.Context, messages []m.OutboxMessage) error {
+	const method = "OutboxRepository.Add"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		INSERT INTO outbox (event_type, payload, occurred_at)
+		VALUES (:event_type, :payload, :occurred_at)
+	`
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+	for chunk := range slices.Chunk(messages, insertBatchSize) {
+		if _, err := sqlx.NamedExecContext(ctx, db, query, chunk); err != nil {
+			slog.Error("failed to add outbox messages",
+				"method", method,
+				"messages_count", len(chunk),
+				"error", err,
+			)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPendingForUpdate locks the oldest unpublished messages. Rows locked by
+// another dispatcher are skipped, so each message is handled by one of them.
+func (r *outboxRepository) GetPendingForUpdate(ctx context.Context, limit int) ([]m.OutboxMessage, error) {
+	const method = "OutboxRepository.GetPendingForUpdate"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		SELECT id, event_type, payload, occurred_at, published_at
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	var messages []m.OutboxMessage
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &messages, query, limit)
+	if err != nil {
+		slog.Error("failed to get pending outbox messages",
+			"method", method,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, ids []int64) error {
+	const method = "OutboxRepository.MarkPublished"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `UPDATE outbox SET published_at = NOW() WHERE id = ANY($1)`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		slog.Error("failed to mark outbox messages published",
+			"method", method,
+			"messages_count", len(ids),
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
@@ -4,20 +4,42 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
+	"slices"
 	"time"
 
 	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	"github.com/jmoiron/sqlx"
 	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// insertBatchSize keeps multi-row inserts well below the PostgreSQL limit of
+// 65535 bind parameters.
+const insertBatchSize = 1000
+
 type PRRepository interface {
 	ExistsByID(ctx context.Context, prID string) (bool, error)
 	GetByID(ctx context.Context, prID string) (*m.PullRequest, error)
 	Create(ctx context.Context, pr *m.PullRequest) error
-	UpdateStatus(ctx context.Context, prID string, status string, mergedAt *time.Time) error
-	UpdateReviewer(ctx context.Context, prID string, oldUserID string, newUserID string) error
+	UpdateStatus(ctx context.Context, prID string, status string, mergedAt *time.Time, closedAt *time.Time) error
+	AddReviewers(ctx context.Context, prID string, reviewers []string, fallbackReviewers map[string]string) error
+	SetMergeForced(ctx context.Context, prID string) error
+	UpdateTitle(ctx context.Context, prID string, title string) error
+	UpdateReviewer(ctx context.Context, prID string, oldUserID string, newUserID string, fallbackTeam *string) error
 	GetByReviewer(ctx context.Context, userID string) ([]m.PullRequestShort, error)
+	GetOpenReviewLoad(ctx context.Context, userIDs []string) (map[string]int, error)
+	GetUnavailableReviewers(ctx context.Context, prID string) ([]m.TeamMember, error)
+	CountOpenByTeam(ctx context.Context) (map[string]int, error)
+	CreateReview(ctx context.Context, review *m.Review) error
+	CreateEvents(ctx context.Context, events []m.PREvent) error
+	GetEvents(ctx context.Context, prID string) ([]m.PREvent, error)
+	GetOpenAssignments(ctx context.Context, reviewerIDs []string) ([]m.OpenAssignment, error)
+	ReplaceReviewers(ctx context.Context, replacements []m.ReviewerReplacement) error
+	QueueReviews(ctx context.Context, reviews []m.QueuedReview) error
+	GetQueuedReviewsForUpdate(ctx context.Context, afterID int64, limit int) ([]m.QueuedReview, error)
+	DeleteQueuedReviews(ctx context.Context, ids []int64) error
 }
 
 type prRepository struct {
@@ -33,6 +55,9 @@ func NewPRRepository(db *sqlx.DB) PRRepository {
 }
 
 func (r *prRepository) ExistsByID(ctx context.Context, prID string) (bool, error) {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.ExistsByID", attribute.String("pr_id", prID))
+	defer span.End()
+
 	query := `SELECT EXISTS(SELECT 1 FROM pull_requests WHERE id = $1)`
 	var exists bool
 
@@ -43,14 +68,20 @@ func (r *prRepository) ExistsByID(ctx context.Context, prID string) (bool, error
 func (r *prRepository) GetByID(ctx context.Context, prID string) (*m.PullRequest, error) {
 	const method = "PRRepository.GetByID"
 
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("pr_id", prID))
+	defer span.End()
+
 	query := `
         SELECT 
             id,
             title, 
             author_id,
+            COALESCE(team_name, '') AS team_name,
             status,
             created_at,
-            merged_at
+            merged_at,
+            merge_forced,
+            closed_at
         FROM pull_requests
         WHERE id = $1
     `
@@ -74,12 +105,15 @@ func (r *prRepository) GetByID(ctx context.Context, prID string) (*m.PullRequest
 	}
 
 	reviewersQuery := `
-        SELECT user_id 
+        SELECT user_id, fallback_team
         FROM pr_reviewers 
         WHERE pr_id = $1
         ORDER BY assigned_at
     `
-	var reviewers []string
+	var reviewers []struct {
+		UserID       string  `db:"user_id"`
+		FallbackTeam *string `db:"fallback_team"`
+	}
 
 	err = r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &reviewers, reviewersQuery, prID)
 	if err != nil {
@@ -91,25 +125,59 @@ func (r *prRepository) GetByID(ctx context.Context, prID string) (*m.PullRequest
 		return nil, err
 	}
 
-	if reviewers == nil {
-		reviewers = []string{}
+	reviewsQuery := `
+        SELECT
+            pr_id,
+            reviewer_id,
+            state,
+            comment,
+            submitted_at
+        FROM pr_reviews
+        WHERE pr_id = $1
+        ORDER BY submitted_at, id
+    `
+	var reviews []m.Review
+
+	err = r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &reviews, reviewsQuery, prID)
+	if err != nil {
+		slog.Error("failed to get PR reviews",
+			"method", method,
+			"pr_id", prID,
+			"error", err,
+		)
+		return nil, err
+	}
+	pr.Reviews = m.CurrentReviews(reviews)
+
+	pr.AssignedReviewers = make([]string, 0, len(reviewers))
+	for _, reviewer := range reviewers {
+		pr.AssignedReviewers = append(pr.AssignedReviewers, reviewer.UserID)
+		if reviewer.FallbackTeam != nil {
+			if pr.FallbackReviewers == nil {
+				pr.FallbackReviewers = make(map[string]string)
+			}
+			pr.FallbackReviewers[reviewer.UserID] = *reviewer.FallbackTeam
+		}
 	}
-	pr.AssignedReviewers = reviewers
 
 	return &pr, nil
 }
 
 func (r *prRepository) Create(ctx context.Context, pr *m.PullRequest) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.Create", attribute.String("pr_id", pr.PullRequestID))
+	defer span.End()
+
 	db := r.getter.DefaultTrOrDB(ctx, r.db)
 
 	query := `
-        INSERT INTO pull_requests (id, title, author_id, status, created_at)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO pull_requests (id, title, author_id, team_name, status, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
     `
 	_, err := db.ExecContext(ctx, query,
 		pr.PullRequestID,
 		pr.PullRequestName,
 		pr.AuthorID,
+		pr.TeamName,
 		pr.Status,
 		time.Now(),
 	)
@@ -117,37 +185,75 @@ func (r *prRepository) Create(ctx context.Context, pr *m.PullRequest) error {
 		return err
 	}
 
-	if len(pr.AssignedReviewers) > 0 {
-		reviewerQuery := `
-            INSERT INTO pr_reviewers (pr_id, user_id, assigned_at)
-            VALUES ($1, $2, $3)
-        `
-		assignedAt := time.Now()
+	return r.AddReviewers(ctx, pr.PullRequestID, pr.AssignedReviewers, pr.FallbackReviewers)
+}
 
-		for _, reviewerID := range pr.AssignedReviewers {
-			_, err = db.ExecContext(ctx, reviewerQuery,
-				pr.PullRequestID, reviewerID, assignedAt)
-			if err != nil {
-				return err
-			}
+func (r *prRepository) AddReviewers(ctx context.Context, prID string, reviewers []string, fallbackReviewers map[string]string) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.AddReviewers", attribute.String("pr_id", prID))
+	defer span.End()
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+
+	reviewerQuery := `
+        INSERT INTO pr_reviewers (pr_id, user_id, assigned_at, fallback_team)
+        VALUES ($1, $2, $3, $4)
+    `
+	assignedAt := time.Now()
+
+	for _, reviewerID := range reviewers {
+		var fallbackTeam *string
+		if team, ok := fallbackReviewers[reviewerID]; ok {
+			fallbackTeam = &team
+		}
+
+		_, err := db.ExecContext(ctx, reviewerQuery,
+			prID, reviewerID, assignedAt, fallbackTeam)
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (r *prRepository) UpdateStatus(ctx context.Context, prID string, status string, mergedAt *time.Time) error {
+func (r *prRepository) UpdateStatus(ctx context.Context, prID string, status string, mergedAt *time.Time, closedAt *time.Time) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.UpdateStatus", attribute.String("pr_id", prID))
+	defer span.End()
+
 	query := `
         UPDATE pull_requests 
-        SET status = $1, merged_at = $2
-        WHERE id = $3
+        SET status = $1, merged_at = $2, closed_at = $3
+        WHERE id = $4
     `
 
-	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, status, mergedAt, prID)
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, status, mergedAt, closedAt, prID)
 	return err
 }
 
-func (r *prRepository) UpdateReviewer(ctx context.Context, prID string, oldUserID string, newUserID string) error {
+func (r *prRepository) SetMergeForced(ctx context.Context, prID string) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.SetMergeForced", attribute.String("pr_id", prID))
+	defer span.End()
+
+	query := `UPDATE pull_requests SET merge_forced = TRUE WHERE id = $1`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, prID)
+	return err
+}
+
+func (r *prRepository) UpdateTitle(ctx context.Context, prID string, title string) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.UpdateTitle", attribute.String("pr_id", prID))
+	defer span.End()
+
+	query := `UPDATE pull_requests SET title = $1 WHERE id = $2`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, title, prID)
+	return err
+}
+
+func (r *prRepository) UpdateReviewer(ctx context.Context, prID string, oldUserID string, newUserID string, fallbackTeam *string) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.UpdateReviewer", attribute.String("pr_id", prID))
+	defer span.End()
+
 	db := r.getter.DefaultTrOrDB(ctx, r.db)
 
 	_, err := db.ExecContext(ctx,
@@ -159,8 +265,8 @@ func (r *prRepository) UpdateReviewer(ctx context.Context, prID string, oldUserI
 	}
 
 	_, err = db.ExecContext(ctx,
-		"INSERT INTO pr_reviewers (pr_id, user_id, assigned_at) VALUES ($1, $2, $3)",
-		prID, newUserID, time.Now(),
+		"INSERT INTO pr_reviewers (pr_id, user_id, assigned_at, fallback_team) VALUES ($1, $2, $3, $4)",
+		prID, newUserID, time.Now(), fallbackTeam,
 	)
 	if err != nil {
 		return err
@@ -170,6 +276,9 @@ func (r *prRepository) UpdateReviewer(ctx context.Context, prID string, oldUserI
 }
 
 func (r *prRepository) GetByReviewer(ctx context.Context, userID string) ([]m.PullRequestShort, error) {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.GetByReviewer", attribute.String("user_id", userID))
+	defer span.End()
+
 	query := `
         SELECT 
             pr.id,
@@ -194,3 +303,318 @@ func (r *prRepository) GetByReviewer(ctx context.Context, userID string) ([]m.Pu
 
 	return prs, nil
 }
+
+func (r *prRepository) GetOpenReviewLoad(ctx context.Context, userIDs []string) (map[string]int, error) {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.GetOpenReviewLoad")
+	defer span.End()
+
+	query := `
+        SELECT
+            prr.user_id,
+            COUNT(*) AS open_reviews
+        FROM pr_reviewers prr
+            JOIN pull_requests pr ON pr.id = prr.pr_id
+        WHERE pr.status IN ('OPEN', 'REOPENED') AND prr.user_id = ANY($1)
+        GROUP BY prr.user_id
+    `
+	var rows []struct {
+		UserID      string `db:"user_id"`
+		OpenReviews int    `db:"open_reviews"`
+	}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	load := make(map[string]int, len(userIDs))
+	for _, row := range rows {
+		load[row.UserID] = row.OpenReviews
+	}
+
+	return load, nil
+}
+
+// GetUnavailableReviewers returns the reviewers of the PR who are inactive or
+// absent right now.
+func (r *prRepository) GetUnavailableReviewers(ctx context.Context, prID string) ([]m.TeamMember, error) {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.GetUnavailableReviewers", attribute.String("pr_id", prID))
+	defer span.End()
+
+	query := `
+        SELECT
+            u.id,
+            u.name,
+            u.is_active,
+            u.max_open_reviews,
+            absent
+        FROM pr_reviewers prr
+            JOIN users u ON u.id = prr.user_id
+            CROSS JOIN LATERAL (
+                SELECT EXISTS (
+                    SELECT 1
+                    FROM user_absences a
+                    WHERE a.user_id = u.id AND a.starts_at <= NOW() AND a.ends_at > NOW()
+                ) AS absent
+            ) availability
+        WHERE prr.pr_id = $1 AND (NOT u.is_active OR absent)
+        ORDER BY u.id
+    `
+	var reviewers []m.TeamMember
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &reviewers, query, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviewers, nil
+}
+
+func (r *prRepository) CountOpenByTeam(ctx context.Context) (map[string]int, error) {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.CountOpenByTeam")
+	defer span.End()
+
+	query := `
+        SELECT
+            COALESCE(team_name, '') AS team_name,
+            COUNT(*) AS open_prs
+        FROM pull_requests
+        WHERE status IN ('OPEN', 'REOPENED')
+        GROUP BY team_name
+    `
+	var rows []struct {
+		TeamName string `db:"team_name"`
+		OpenPRs  int    `db:"open_prs"`
+	}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.TeamName] = row.OpenPRs
+	}
+
+	return counts, nil
+}
+
+func (r *prRepository) CreateReview(ctx context.Context, review *m.Review) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.CreateReview", attribute.String("pr_id", review.PullRequestID))
+	defer span.End()
+
+	query := `
+        INSERT INTO pr_reviews (pr_id, reviewer_id, state, comment, submitted_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query,
+		review.PullRequestID,
+		review.ReviewerID,
+		review.State,
+		review.Comment,
+		review.SubmittedAt,
+	)
+	return err
+}
+
+func (r *prRepository) CreateEvents(ctx context.Context, events []m.PREvent) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.CreateEvents")
+	defer span.End()
+
+	query := `
+        INSERT INTO pr_events (pr_id, event_type, reviewer_id, previous_reviewer_id, fallback_team, actor_id, reason)
+        VALUES (:pr_id, :event_type, NULLIF(:reviewer_id, ''), :previous_reviewer_id, :fallback_team, :actor_id, :reason)
+    `
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+	for chunk := range slices.Chunk(events, insertBatchSize) {
+		if _, err := sqlx.NamedExecContext(ctx, db, query, chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *prRepository) GetEvents(ctx context.Context, prID string) ([]m.PREvent, error) {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.GetEvents", attribute.String("pr_id", prID))
+	defer span.End()
+
+	query := `
+        SELECT
+            id,
+            pr_id,
+            event_type,
+            COALESCE(reviewer_id, '') AS reviewer_id,
+            previous_reviewer_id,
+            fallback_team,
+            actor_id,
+            reason,
+            created_at
+        FROM pr_events
+        WHERE pr_id = $1
+        ORDER BY created_at, id
+    `
+	var events []m.PREvent
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &events, query, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	if events == nil {
+		return []m.PREvent{}, nil
+	}
+
+	return events, nil
+}
+
+func (r *prRepository) GetOpenAssignments(ctx context.Context, reviewerIDs []string) ([]m.OpenAssignment, error) {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.GetOpenAssignments")
+	defer span.End()
+
+	query := `
+        SELECT
+            pr.id,
+            pr.title,
+            pr.author_id,
+            pr.status,
+            COALESCE(pr.team_name, '') AS team_name,
+            prr.user_id AS reviewer_id,
+            ARRAY(
+                SELECT all_prr.user_id
+                FROM pr_reviewers all_prr
+                WHERE all_prr.pr_id = pr.id
+                ORDER BY all_prr.assigned_at, all_prr.user_id
+            ) AS reviewers
+        FROM pr_reviewers prr
+            JOIN pull_requests pr ON pr.id = prr.pr_id
+        WHERE pr.status IN ('OPEN', 'REOPENED') AND prr.user_id = ANY($1)
+        ORDER BY pr.created_at, pr.id, prr.user_id
+    `
+	var rows []struct {
+		m.PullRequestShort
+		TeamName   string         `db:"team_name"`
+		ReviewerID string         `db:"reviewer_id"`
+		Reviewers  pq.StringArray `db:"reviewers"`
+	}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query, pq.Array(reviewerIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := make([]m.OpenAssignment, len(rows))
+	for i, row := range rows {
+		assignments[i] = m.OpenAssignment{
+			PullRequestShort: row.PullRequestShort,
+			TeamName:         row.TeamName,
+			ReviewerID:       row.ReviewerID,
+			Reviewers:        row.Reviewers,
+		}
+	}
+
+	return assignments, nil
+}
+
+// ReplaceReviewers applies all replacements with a single statement.
+func (r *prRepository) ReplaceReviewers(ctx context.Context, replacements []m.ReviewerReplacement) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.ReplaceReviewers")
+	defer span.End()
+
+	if len(replacements) == 0 {
+		return nil
+	}
+
+	prIDs := make([]string, len(replacements))
+	oldIDs := make([]string, len(replacements))
+	newIDs := make([]string, len(replacements))
+	fallbackTeams := make([]sql.NullString, len(replacements))
+	for i, replacement := range replacements {
+		prIDs[i] = replacement.PullRequestID
+		oldIDs[i] = replacement.OldReviewerID
+		newIDs[i] = replacement.NewReviewerID
+		if replacement.FallbackTeam != nil {
+			fallbackTeams[i] = sql.NullString{String: *replacement.FallbackTeam, Valid: true}
+		}
+	}
+
+	query := `
+        UPDATE pr_reviewers prr
+        SET user_id = v.new_user_id, fallback_team = v.fallback_team, assigned_at = NOW()
+        FROM unnest($1::varchar[], $2::varchar[], $3::varchar[], $4::varchar[])
+            AS v(pr_id, old_user_id, new_user_id, fallback_team)
+        WHERE prr.pr_id = v.pr_id AND prr.user_id = v.old_user_id
+    `
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query,
+		pq.Array(prIDs),
+		pq.Array(oldIDs),
+		pq.Array(newIDs),
+		pq.Array(fallbackTeams),
+	)
+	return err
+}
+
+// QueueReviews stores review slots to be filled later. A replacement that is
+// already queued for the same reviewer is kept as is.
+func (r *prRepository) QueueReviews(ctx context.Context, reviews []m.QueuedReview) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.QueueReviews")
+	defer span.End()
+
+	query := `
+        INSERT INTO review_queue (pr_id, replaces_reviewer_id, reason, actor_id)
+        VALUES (:pr_id, :replaces_reviewer_id, :reason, :actor_id)
+        ON CONFLICT (pr_id, replaces_reviewer_id) WHERE replaces_reviewer_id IS NOT NULL
+        DO NOTHING
+    `
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+	for chunk := range slices.Chunk(reviews, insertBatchSize) {
+		if _, err := sqlx.NamedExecContext(ctx, db, query, chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetQueuedReviewsForUpdate locks the queued review slots following afterID,
+// oldest first. Rows locked by another worker are skipped.
+func (r *prRepository) GetQueuedReviewsForUpdate(ctx context.Context, afterID int64, limit int) ([]m.QueuedReview, error) {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.GetQueuedReviewsForUpdate")
+	defer span.End()
+
+	query := `
+        SELECT id, pr_id, replaces_reviewer_id, reason, actor_id, created_at
+        FROM review_queue
+        WHERE id > $1
+        ORDER BY id
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED
+    `
+	var reviews []m.QueuedReview
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &reviews, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+func (r *prRepository) DeleteQueuedReviews(ctx context.Context, ids []int64) error {
+	ctx, span := tracing.StartQuery(ctx, "PRRepository.DeleteQueuedReviews")
+	defer span.End()
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `DELETE FROM review_queue WHERE id = ANY($1)`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
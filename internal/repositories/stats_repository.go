@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"log/slog"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/jmoiron/sqlx"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"github.com/lib/pq"
+)
+
+type StatsRepository interface {
+	GetReviewerStats(ctx context.Context, filter m.ReviewerStatsFilter) ([]m.ReviewerStats, error)
+}
+
+type statsRepository struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewStatsRepository(db *sqlx.DB) StatsRepository {
+	return &statsRepository{
+		db:     db,
+		getter: trmsqlx.DefaultCtxGetter,
+	}
+}
+
+// GetReviewerStats aggregates assignments per user. Replaced assignments are
+// gone from pr_reviewers, so they are counted from the assignment history and
+// added to the total.
+func (r *statsRepository) GetReviewerStats(ctx context.Context, filter m.ReviewerStatsFilter) ([]m.ReviewerStats, error) {
+	const method = "StatsRepository.GetReviewerStats"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		WITH assigned AS (
+			SELECT
+				prr.user_id,
+				COUNT(*) AS current_assignments,
+				COUNT(*) FILTER (WHERE pr.status IN ('OPEN', 'REOPENED')) AS open_assignments,
+				COUNT(*) FILTER (WHERE pr.status = 'MERGED') AS merged_reviewed
+			FROM pr_reviewers prr
+				JOIN pull_requests pr ON pr.id = prr.pr_id
+			WHERE ($1::varchar IS NULL OR pr.team_name = $1)
+				AND ($2::timestamptz IS NULL OR prr.assigned_at >= $2)
+				AND ($3::timestamptz IS NULL OR prr.assigned_at < $3)
+			GROUP BY prr.user_id
+		), reassigned AS (
+			SELECT
+				e.previous_reviewer_id AS user_id,
+				COUNT(*) AS reassigned_away
+			FROM pr_events e
+				JOIN pull_requests pr ON pr.id = e.pr_id
+			WHERE e.event_type = 'REPLACED'
+				AND ($1::varchar IS NULL OR pr.team_name = $1)
+				AND ($2::timestamptz IS NULL OR e.created_at >= $2)
+				AND ($3::timestamptz IS NULL OR e.created_at < $3)
+			GROUP BY e.previous_reviewer_id
+		)
+		SELECT
+			u.id AS user_id,
+			u.name AS username,
+			ARRAY(
+				SELECT tm.team_name
+				FROM team_memberships tm
+				WHERE tm.user_id = u.id
+				ORDER BY tm.joined_at, tm.team_name
+			) AS teams,
+			COALESCE(a.current_assignments, 0) + COALESCE(r.reassigned_away, 0) AS total_assignments,
+			COALESCE(a.open_assignments, 0) AS open_assignments,
+			COALESCE(a.merged_reviewed, 0) AS merged_reviewed,
+			COALESCE(r.reassigned_away, 0) AS reassigned_away
+		FROM users u
+			LEFT JOIN assigned a ON a.user_id = u.id
+			LEFT JOIN reassigned r ON r.user_id = u.id
+		WHERE $1::varchar IS NULL
+			OR EXISTS (SELECT 1 FROM team_memberships tm WHERE tm.user_id = u.id AND tm.team_name = $1)
+		ORDER BY u.id
+	`
+	var rows []struct {
+		m.ReviewerStats
+		Teams pq.StringArray `db:"teams"`
+	}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query, filter.TeamName, filter.From, filter.To)
+	if err != nil {
+		slog.Error("failed to get reviewer stats",
+			"method", method,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	stats := make([]m.ReviewerStats, len(rows))
+	for i, row := range rows {
+		stats[i] = row.ReviewerStats
+		stats[i].Teams = []string(row.Teams)
+	}
+
+	return stats, nil
+}
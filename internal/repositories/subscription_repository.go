@@ -0,0 +1,374 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
+	"github.com/jmoiron/sqlx"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type SubscriptionRepository interface {
+	Create(ctx context.Context, subscription *m.Subscription) error
+	ExistsByID(ctx context.Context, subscriptionID int64) (bool, error)
+	GetAll(ctx context.Context) ([]m.Subscription, error)
+	Deactivate(ctx context.Context, subscriptionID int64) (*m.Subscription, error)
+	FailPendingDeliveries(ctx context.Context, subscriptionID int64, reason string) error
+	GetActiveByEvent(ctx context.Context, eventType m.EventType) ([]m.Subscription, error)
+	CreateDeliveries(ctx context.Context, deliveries []m.WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]m.DueDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *m.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]m.WebhookDelivery, error)
+}
+
+type subscriptionRepository struct {
+	db     *sqlx.DB
+	getter *trmsqlx.CtxGetter
+}
+
+func NewSubscriptionRepository(db *sqlx.DB) SubscriptionRepository {
+	return &subscriptionRepository{
+		db:     db,
+		getter: trmsqlx.DefaultCtxGetter,
+	}
+}
+
+type subscriptionRow struct {
+	m.Subscription
+	Events pq.StringArray `db:"events"`
+}
+
+func (row subscriptionRow) toModel() m.Subscription {
+	subscription := row.Subscription
+	subscription.Events = make([]m.EventType, len(row.Events))
+	for i, event := range row.Events {
+		subscription.Events[i] = m.EventType(event)
+	}
+	return subscription
+}
+
+func (r *subscriptionRepository) Create(ctx context.Context, subscription *m.Subscription) error {
+	const method = "SubscriptionRepository.Create"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	events := make(pq.StringArray, len(subscription.Events))
+	for i, event := range subscription.Events {
+		events[i] = string(event)
+	}
+
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, events, is_active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).QueryRowxContext(ctx, query,
+		subscription.URL,
+		subscription.Secret,
+		events,
+		subscription.IsActive,
+	).Scan(&subscription.ID, &subscription.CreatedAt)
+	if err != nil {
+		slog.Error("failed to create subscription",
+			"method", method,
+			"url", subscription.URL,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionRepository) ExistsByID(ctx context.Context, subscriptionID int64) (bool, error) {
+	ctx, span := tracing.StartQuery(ctx, "SubscriptionRepository.ExistsByID", attribute.Int64("subscription_id", subscriptionID))
+	defer span.End()
+
+	query := `SELECT EXISTS(SELECT 1 FROM webhook_subscriptions WHERE id = $1)`
+	var exists bool
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &exists, query, subscriptionID)
+	return exists, err
+}
+
+func (r *subscriptionRepository) GetAll(ctx context.Context) ([]m.Subscription, error) {
+	const method = "SubscriptionRepository.GetAll"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		SELECT id, url, secret, events, is_active, created_at
+		FROM webhook_subscriptions
+		ORDER BY id
+	`
+	var rows []subscriptionRow
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query)
+	if err != nil {
+		slog.Error("failed to get subscriptions",
+			"method", method,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	subscriptions := make([]m.Subscription, len(rows))
+	for i, row := range rows {
+		subscriptions[i] = row.toModel()
+	}
+
+	return subscriptions, nil
+}
+
+// Deactivate stops the subscription from receiving new events. A nil
+// subscription is returned when it does not exist.
+func (r *subscriptionRepository) Deactivate(ctx context.Context, subscriptionID int64) (*m.Subscription, error) {
+	const method = "SubscriptionRepository.Deactivate"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.Int64("subscription_id", subscriptionID))
+	defer span.End()
+
+	query := `
+		UPDATE webhook_subscriptions
+		SET is_active = FALSE
+		WHERE id = $1
+		RETURNING id, url, secret, events, is_active, created_at
+	`
+	var row subscriptionRow
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &row, query, subscriptionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.Error("failed to deactivate subscription",
+			"method", method,
+			"subscription_id", subscriptionID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	subscription := row.toModel()
+	return &subscription, nil
+}
+
+// FailPendingDeliveries gives up the deliveries of the subscription that have
+// not been sent yet.
+func (r *subscriptionRepository) FailPendingDeliveries(ctx context.Context, subscriptionID int64, reason string) error {
+	const method = "SubscriptionRepository.FailPendingDeliveries"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.Int64("subscription_id", subscriptionID))
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'FAILED', next_attempt_at = NULL, last_error = $2
+		WHERE subscription_id = $1 AND status = 'PENDING'
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, subscriptionID, reason)
+	if err != nil {
+		slog.Error("failed to fail pending deliveries",
+			"method", method,
+			"subscription_id", subscriptionID,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionRepository) GetActiveByEvent(ctx context.Context, eventType m.EventType) ([]m.Subscription, error) {
+	const method = "SubscriptionRepository.GetActiveByEvent"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("event_type", string(eventType)))
+	defer span.End()
+
+	query := `
+		SELECT id, url, secret, events, is_active, created_at
+		FROM webhook_subscriptions
+		WHERE is_active AND $1 = ANY(events)
+	`
+	var rows []subscriptionRow
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query, eventType)
+	if err != nil {
+		slog.Error("failed to get subscriptions",
+			"method", method,
+			"event_type", eventType,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	subscriptions := make([]m.Subscription, len(rows))
+	for i, row := range rows {
+		subscriptions[i] = row.toModel()
+	}
+
+	return subscriptions, nil
+}
+
+func (r *subscriptionRepository) CreateDeliveries(ctx context.Context, deliveries []m.WebhookDelivery) error {
+	const method = "SubscriptionRepository.CreateDeliveries"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_type, payload, status)
+		VALUES (:subscription_id, :event_type, :payload, :status)
+	`
+
+	_, err := sqlx.NamedExecContext(ctx, r.getter.DefaultTrOrDB(ctx, r.db), query, deliveries)
+	if err != nil {
+		slog.Error("failed to create deliveries",
+			"method", method,
+			"deliveries_count", len(deliveries),
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDueDeliveries takes pending deliveries whose attempt time has come and
+// postpones them by lease, so that concurrent dispatchers skip them while
+// they are being sent.
+func (r *subscriptionRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]m.DueDelivery, error) {
+	const method = "SubscriptionRepository.ClaimDueDeliveries"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING
+			d.id,
+			d.subscription_id,
+			d.event_type,
+			d.payload,
+			d.status,
+			d.attempts,
+			d.next_attempt_at,
+			d.response_status,
+			d.last_error,
+			d.created_at,
+			d.delivered_at,
+			s.url,
+			s.secret
+	`
+	var deliveries []m.DueDelivery
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &deliveries, query, limit, lease.Seconds())
+	if err != nil {
+		slog.Error("failed to claim due deliveries",
+			"method", method,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *subscriptionRepository) UpdateDelivery(ctx context.Context, delivery *m.WebhookDelivery) error {
+	const method = "SubscriptionRepository.UpdateDelivery"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET
+			status = :status,
+			attempts = :attempts,
+			next_attempt_at = :next_attempt_at,
+			response_status = :response_status,
+			last_error = :last_error,
+			delivered_at = :delivered_at
+		WHERE id = :id
+	`
+
+	_, err := sqlx.NamedExecContext(ctx, r.getter.DefaultTrOrDB(ctx, r.db), query, delivery)
+	if err != nil {
+		slog.Error("failed to update delivery",
+			"method", method,
+			"delivery_id", delivery.ID,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *subscriptionRepository) GetDeliveries(ctx context.Context, subscriptionID int64, limit int) ([]m.WebhookDelivery, error) {
+	const method = "SubscriptionRepository.GetDeliveries"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.Int64("subscription_id", subscriptionID))
+	defer span.End()
+
+	query := `
+		SELECT
+			id,
+			subscription_id,
+			event_type,
+			payload,
+			status,
+			attempts,
+			next_attempt_at,
+			response_status,
+			last_error,
+			created_at,
+			delivered_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+	var deliveries []m.WebhookDelivery
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &deliveries, query, subscriptionID, limit)
+	if err != nil {
+		slog.Error("failed to get deliveries",
+			"method", method,
+			"subscription_id", subscriptionID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	if deliveries == nil {
+		return []m.WebhookDelivery{}, nil
+	}
+
+	return deliveries, nil
+}
@@ -2,17 +2,32 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
 	"log/slog"
 
 	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	"github.com/jmoiron/sqlx"
 	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TeamRepository interface {
 	ExistsByName(ctx context.Context, name string) (bool, error)
 	GetTeamByName(ctx context.Context, name string) (*m.Team, error)
 	CreateTeam(ctx context.Context, team *m.Team) error
+	UpdateTeam(ctx context.Context, team *m.Team) error
+	DeleteTeam(ctx context.Context, name string) error
+	AddMember(ctx context.Context, teamName string, member m.TeamMember) error
+	RemoveMember(ctx context.Context, teamName string, userID string) error
+	MoveMember(ctx context.Context, userID string, fromTeam string, toTeam string) error
+	GetMemberTeams(ctx context.Context, userIDs []string) (map[string][]string, error)
+	OpenMemberships(ctx context.Context, teamName string, userIDs []string, actorID *string) error
+	CloseMemberships(ctx context.Context, teamName string, userIDs []string) error
+	GetMembershipHistory(ctx context.Context, userID string) ([]m.TeamMembership, error)
+	GetRotationCursorForUpdate(ctx context.Context, name string) (*string, error)
+	UpdateRotationCursor(ctx context.Context, name string, userID string) error
 }
 
 type teamRepository struct {
@@ -30,6 +45,9 @@ func NewTeamRepository(db *sqlx.DB) TeamRepository {
 func (r *teamRepository) ExistsByName(ctx context.Context, name string) (bool, error) {
 	const method = "TeamRepository.ExistsByName"
 
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", name))
+	defer span.End()
+
 	query := `SELECT EXISTS(SELECT 1 FROM teams WHERE name = $1)`
 	var exists bool
 
@@ -49,23 +67,49 @@ func (r *teamRepository) ExistsByName(ctx context.Context, name string) (bool, e
 func (r *teamRepository) GetTeamByName(ctx context.Context, name string) (*m.Team, error) {
 	const method = "TeamRepository.GetTeamByName"
 
-	exists, err := r.ExistsByName(ctx, name)
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", name))
+	defer span.End()
+
+	teamQuery := `
+		SELECT name, selection_strategy, reviewers_required, approvals_required,
+			max_open_reviews, capacity_policy, rotation_cursor
+		FROM teams
+		WHERE name = $1
+	`
+	var team m.Team
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &team, teamQuery, name)
 	if err != nil {
-		return nil, err
-	}
-	if !exists {
-		slog.Warn("team not found",
+		if err == sql.ErrNoRows {
+			slog.Warn("team not found",
+				"method", method,
+				"team_name", name,
+			)
+			return nil, nil
+		}
+		slog.Error("failed to get team",
 			"method", method,
 			"team_name", name,
+			"error", err,
 		)
-		return nil, nil
+		return nil, err
 	}
 
 	query := `
-		SELECT id, name, is_active 
-		FROM users 
-		WHERE team_name = $1 
-		ORDER BY name
+		SELECT
+			u.id,
+			u.name,
+			u.is_active,
+			u.max_open_reviews,
+			EXISTS (
+				SELECT 1
+				FROM user_absences a
+				WHERE a.user_id = u.id AND a.starts_at <= NOW() AND a.ends_at > NOW()
+			) AS absent
+		FROM users u
+		JOIN team_memberships tm ON tm.user_id = u.id
+		WHERE tm.team_name = $1
+		ORDER BY u.name, u.id
 	`
 	var members []m.TeamMember
 
@@ -79,19 +123,50 @@ func (r *teamRepository) GetTeamByName(ctx context.Context, name string) (*m.Tea
 		return nil, err
 	}
 
-	return &m.Team{
-		TeamName: name,
-		Members:  members,
-	}, nil
+	fallbacksQuery := `
+		SELECT fallback_team_name
+		FROM team_fallbacks
+		WHERE team_name = $1
+		ORDER BY priority
+	`
+	var fallbackTeams []string
+
+	err = r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &fallbackTeams, fallbacksQuery, name)
+	if err != nil {
+		slog.Error("failed to get team fallbacks",
+			"method", method,
+			"team_name", name,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	team.Members = members
+	team.FallbackTeams = fallbackTeams
+
+	return &team, nil
 }
 
 func (r *teamRepository) CreateTeam(ctx context.Context, team *m.Team) error {
 	const method = "TeamRepository.CreateTeam"
 
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", team.TeamName))
+	defer span.End()
+
 	db := r.getter.DefaultTrOrDB(ctx, r.db)
 
-	insertTeamQuery := `INSERT INTO teams (name) VALUES ($1)`
-	_, err := db.ExecContext(ctx, insertTeamQuery, team.TeamName)
+	insertTeamQuery := `
+		INSERT INTO teams (name, selection_strategy, reviewers_required, approvals_required, max_open_reviews, capacity_policy)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := db.ExecContext(ctx, insertTeamQuery,
+		team.TeamName,
+		team.SelectionStrategy,
+		team.ReviewersRequired,
+		team.ApprovalsRequired,
+		team.MaxOpenReviews,
+		team.CapacityPolicy,
+	)
 	if err != nil {
 		slog.Error("failed to insert team",
 			"method", method,
@@ -101,29 +176,34 @@ func (r *teamRepository) CreateTeam(ctx context.Context, team *m.Team) error {
 		return err
 	}
 
-	if len(team.Members) > 0 {
-		usersRows := make([]m.User, len(team.Members))
-
-		for i, member := range team.Members {
-			usersRows[i] = m.User{
-				UserID:   member.UserID,
-				Username: member.Username,
-				TeamName: team.TeamName,
-				IsActive: member.IsActive,
-			}
+	insertFallbackQuery := `
+		INSERT INTO team_fallbacks (team_name, fallback_team_name, priority)
+		VALUES ($1, $2, $3)
+	`
+	for priority, fallbackTeam := range team.FallbackTeams {
+		_, err = db.ExecContext(ctx, insertFallbackQuery, team.TeamName, fallbackTeam, priority)
+		if err != nil {
+			slog.Error("failed to insert team fallback",
+				"method", method,
+				"team_name", team.TeamName,
+				"fallback_team", fallbackTeam,
+				"error", err,
+			)
+			return err
 		}
+	}
 
+	if len(team.Members) > 0 {
 		insertUsersBatchQuery := `
-			INSERT INTO users (id, name, team_name, is_active)
-			VALUES (:id, :name, :team_name, :is_active)
+			INSERT INTO users (id, name, is_active)
+			VALUES (:id, :name, :is_active)
 			ON CONFLICT (id) 
 			DO UPDATE SET
 				name = EXCLUDED.name,
-				team_name = EXCLUDED.team_name,
 				is_active = EXCLUDED.is_active
 		`
 
-		_, err = sqlx.NamedExecContext(ctx, db, insertUsersBatchQuery, usersRows)
+		_, err = sqlx.NamedExecContext(ctx, db, insertUsersBatchQuery, team.Members)
 		if err != nil {
 			slog.Error("failed to insert team members",
 				"method", method,
@@ -133,6 +213,384 @@ func (r *teamRepository) CreateTeam(ctx context.Context, team *m.Team) error {
 			)
 			return err
 		}
+
+		memberIDs := make([]string, len(team.Members))
+		for i, member := range team.Members {
+			memberIDs[i] = member.UserID
+		}
+
+		insertMembershipsQuery := `
+			INSERT INTO team_memberships (user_id, team_name)
+			SELECT user_id, $2
+			FROM unnest($1::varchar[]) AS user_id
+		`
+
+		_, err = db.ExecContext(ctx, insertMembershipsQuery, pq.Array(memberIDs), team.TeamName)
+		if err != nil {
+			slog.Error("failed to insert team memberships",
+				"method", method,
+				"team_name", team.TeamName,
+				"members_count", len(team.Members),
+				"error", err,
+			)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateTeam stores the settings of the team and replaces its fallback teams.
+func (r *teamRepository) UpdateTeam(ctx context.Context, team *m.Team) error {
+	const method = "TeamRepository.UpdateTeam"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", team.TeamName))
+	defer span.End()
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+
+	updateTeamQuery := `
+		UPDATE teams
+		SET selection_strategy = $1, reviewers_required = $2, approvals_required = $3,
+			max_open_reviews = $4, capacity_policy = $5
+		WHERE name = $6
+	`
+	_, err := db.ExecContext(ctx, updateTeamQuery,
+		team.SelectionStrategy,
+		team.ReviewersRequired,
+		team.ApprovalsRequired,
+		team.MaxOpenReviews,
+		team.CapacityPolicy,
+		team.TeamName,
+	)
+	if err != nil {
+		slog.Error("failed to update team",
+			"method", method,
+			"team_name", team.TeamName,
+			"error", err,
+		)
+		return err
+	}
+
+	deleteFallbacksQuery := `DELETE FROM team_fallbacks WHERE team_name = $1`
+	if _, err = db.ExecContext(ctx, deleteFallbacksQuery, team.TeamName); err != nil {
+		slog.Error("failed to delete team fallbacks",
+			"method", method,
+			"team_name", team.TeamName,
+			"error", err,
+		)
+		return err
+	}
+
+	insertFallbackQuery := `
+		INSERT INTO team_fallbacks (team_name, fallback_team_name, priority)
+		VALUES ($1, $2, $3)
+	`
+	for priority, fallbackTeam := range team.FallbackTeams {
+		_, err = db.ExecContext(ctx, insertFallbackQuery, team.TeamName, fallbackTeam, priority)
+		if err != nil {
+			slog.Error("failed to insert team fallback",
+				"method", method,
+				"team_name", team.TeamName,
+				"fallback_team", fallbackTeam,
+				"error", err,
+			)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteTeam deletes the team together with its fallback settings and its
+// entries in the fallback lists of other teams. Members are not deleted: the
+// foreign key on team_memberships rejects deleting a team that still has them.
+func (r *teamRepository) DeleteTeam(ctx context.Context, name string) error {
+	const method = "TeamRepository.DeleteTeam"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", name))
+	defer span.End()
+
+	query := `DELETE FROM teams WHERE name = $1`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, name)
+	if err != nil {
+		slog.Error("failed to delete team",
+			"method", method,
+			"team_name", name,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// AddMember creates the user if needed and adds them to the team. Existing
+// users are left as they are and keep their other teams.
+func (r *teamRepository) AddMember(ctx context.Context, teamName string, member m.TeamMember) error {
+	const method = "TeamRepository.AddMember"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", teamName), attribute.String("user_id", member.UserID))
+	defer span.End()
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+
+	userQuery := `
+		INSERT INTO users (id, name, is_active)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO NOTHING
+	`
+
+	_, err := db.ExecContext(ctx, userQuery, member.UserID, member.Username, member.IsActive)
+	if err != nil {
+		slog.Error("failed to create team member",
+			"method", method,
+			"team_name", teamName,
+			"user_id", member.UserID,
+			"error", err,
+		)
+		return err
+	}
+
+	membershipQuery := `INSERT INTO team_memberships (user_id, team_name) VALUES ($1, $2)`
+	if _, err = db.ExecContext(ctx, membershipQuery, member.UserID, teamName); err != nil {
+		slog.Error("failed to add team member",
+			"method", method,
+			"team_name", teamName,
+			"user_id", member.UserID,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// RemoveMember detaches the user from the team. A rotation cursor pointing at
+// them is kept, so the rotation resumes after their position.
+func (r *teamRepository) RemoveMember(ctx context.Context, teamName string, userID string) error {
+	const method = "TeamRepository.RemoveMember"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", teamName), attribute.String("user_id", userID))
+	defer span.End()
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+
+	removeQuery := `DELETE FROM team_memberships WHERE user_id = $1 AND team_name = $2`
+	if _, err := db.ExecContext(ctx, removeQuery, userID, teamName); err != nil {
+		slog.Error("failed to remove team member",
+			"method", method,
+			"team_name", teamName,
+			"user_id", userID,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// MoveMember moves the user's membership from one team to another. The
+// rotation cursor of the team they left is kept, as in RemoveMember.
+func (r *teamRepository) MoveMember(ctx context.Context, userID string, fromTeam string, toTeam string) error {
+	const method = "TeamRepository.MoveMember"
+
+	ctx, span := tracing.StartQuery(ctx, method,
+		attribute.String("from_team", fromTeam),
+		attribute.String("to_team", toTeam),
+		attribute.String("user_id", userID),
+	)
+	defer span.End()
+
+	db := r.getter.DefaultTrOrDB(ctx, r.db)
+
+	query := `
+		UPDATE team_memberships
+		SET team_name = $1, joined_at = NOW()
+		WHERE user_id = $2 AND team_name = $3
+	`
+	if _, err := db.ExecContext(ctx, query, toTeam, userID, fromTeam); err != nil {
+		slog.Error("failed to move team member",
+			"method", method,
+			"from_team", fromTeam,
+			"to_team", toTeam,
+			"user_id", userID,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// GetMemberTeams returns the teams of each of the users that exist and belong
+// to at least one team.
+func (r *teamRepository) GetMemberTeams(ctx context.Context, userIDs []string) (map[string][]string, error) {
+	const method = "TeamRepository.GetMemberTeams"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		SELECT user_id, team_name
+		FROM team_memberships
+		WHERE user_id = ANY($1)
+		ORDER BY joined_at, team_name
+	`
+	var rows []struct {
+		UserID   string `db:"user_id"`
+		TeamName string `db:"team_name"`
+	}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query, pq.Array(userIDs))
+	if err != nil {
+		slog.Error("failed to get member teams",
+			"method", method,
+			"users_count", len(userIDs),
+			"error", err,
+		)
+		return nil, err
+	}
+
+	teams := make(map[string][]string)
+	for _, row := range rows {
+		teams[row.UserID] = append(teams[row.UserID], row.TeamName)
+	}
+
+	return teams, nil
+}
+
+// OpenMemberships starts a membership history entry in the team for each of
+// the users.
+func (r *teamRepository) OpenMemberships(ctx context.Context, teamName string, userIDs []string, actorID *string) error {
+	const method = "TeamRepository.OpenMemberships"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", teamName))
+	defer span.End()
+
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO team_membership_history (user_id, team_name, actor_id)
+		SELECT user_id, $2, $3
+		FROM unnest($1::varchar[]) AS user_id
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, pq.Array(userIDs), teamName, actorID)
+	if err != nil {
+		slog.Error("failed to open team memberships",
+			"method", method,
+			"team_name", teamName,
+			"users_count", len(userIDs),
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+// CloseMemberships ends the current membership history entries of the users
+// in the team.
+func (r *teamRepository) CloseMemberships(ctx context.Context, teamName string, userIDs []string) error {
+	const method = "TeamRepository.CloseMemberships"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", teamName))
+	defer span.End()
+
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE team_membership_history
+		SET left_at = NOW()
+		WHERE user_id = ANY($1) AND team_name = $2 AND left_at IS NULL
+	`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, pq.Array(userIDs), teamName)
+	if err != nil {
+		slog.Error("failed to close team memberships",
+			"method", method,
+			"team_name", teamName,
+			"users_count", len(userIDs),
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (r *teamRepository) GetMembershipHistory(ctx context.Context, userID string) ([]m.TeamMembership, error) {
+	const method = "TeamRepository.GetMembershipHistory"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
+	query := `
+		SELECT team_name, joined_at, left_at, actor_id
+		FROM team_membership_history
+		WHERE user_id = $1
+		ORDER BY joined_at, id
+	`
+	memberships := []m.TeamMembership{}
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &memberships, query, userID)
+	if err != nil {
+		slog.Error("failed to get team membership history",
+			"method", method,
+			"user_id", userID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+func (r *teamRepository) GetRotationCursorForUpdate(ctx context.Context, name string) (*string, error) {
+	const method = "TeamRepository.GetRotationCursorForUpdate"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", name))
+	defer span.End()
+
+	query := `SELECT rotation_cursor FROM teams WHERE name = $1 FOR UPDATE`
+	var cursor *string
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &cursor, query, name)
+	if err != nil {
+		slog.Error("failed to get rotation cursor",
+			"method", method,
+			"team_name", name,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	return cursor, nil
+}
+
+func (r *teamRepository) UpdateRotationCursor(ctx context.Context, name string, userID string) error {
+	const method = "TeamRepository.UpdateRotationCursor"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("team_name", name), attribute.String("user_id", userID))
+	defer span.End()
+
+	query := `UPDATE teams SET rotation_cursor = $1 WHERE name = $2`
+
+	_, err := r.getter.DefaultTrOrDB(ctx, r.db).ExecContext(ctx, query, userID, name)
+	if err != nil {
+		slog.Error("failed to update rotation cursor",
+			"method", method,
+			"team_name", name,
+			"user_id", userID,
+			"error", err,
+		)
+		return err
 	}
 
 	return nil
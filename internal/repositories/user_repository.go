@@ -8,12 +8,17 @@ import (
 	trmsqlx "github.com/avito-tech/go-transaction-manager/drivers/sqlx/v2"
 	"github.com/jmoiron/sqlx"
 	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type UserRepository interface {
 	ExistsByID(ctx context.Context, userID string) (bool, error)
 	GetByID(ctx context.Context, userID string) (*m.User, error)
 	SetIsActive(ctx context.Context, userID string, isActive bool) (*m.User, error)
+	SetMaxOpenReviews(ctx context.Context, userID string, maxOpenReviews *int) (*m.User, error)
+	DeactivateMany(ctx context.Context, userIDs []string) ([]m.User, error)
 }
 
 type userRepository struct {
@@ -28,9 +33,41 @@ func NewUserRepository(db *sqlx.DB) UserRepository {
 	}
 }
 
+// userColumns selects a user together with their teams, ordered by when the
+// user joined them.
+const userColumns = `
+	id,
+	name,
+	ARRAY(
+		SELECT tm.team_name
+		FROM team_memberships tm
+		WHERE tm.user_id = users.id
+		ORDER BY tm.joined_at, tm.team_name
+	) AS teams,
+	is_active,
+	max_open_reviews
+`
+
+type userRow struct {
+	m.User
+	Teams pq.StringArray `db:"teams"`
+}
+
+func (row userRow) toModel() m.User {
+	user := row.User
+	user.Teams = []string(row.Teams)
+	if user.Teams == nil {
+		user.Teams = []string{}
+	}
+	return user
+}
+
 func (r *userRepository) ExistsByID(ctx context.Context, userID string) (bool, error) {
 	const method = "UserRepository.ExistsByID"
 
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`
 	var exists bool
 
@@ -50,18 +87,13 @@ func (r *userRepository) ExistsByID(ctx context.Context, userID string) (bool, e
 func (r *userRepository) GetByID(ctx context.Context, userID string) (*m.User, error) {
 	const method = "UserRepository.GetByID"
 
-	query := `
-		SELECT 
-			id, 
-			name, 
-			team_name, 
-			is_active
-		FROM users 
-		WHERE id = $1
-	`
-	var user m.User
-
-	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &user, query, userID)
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	var row userRow
+
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &row, query, userID)
 	if err != nil {
 		slog.Error("failed to get user by ID",
 			"method", method,
@@ -74,21 +106,24 @@ func (r *userRepository) GetByID(ctx context.Context, userID string) (*m.User, e
 		return nil, err
 	}
 
+	user := row.toModel()
 	return &user, nil
 }
 
 func (r *userRepository) SetIsActive(ctx context.Context, userID string, isActive bool) (*m.User, error) {
 	const method = "UserRepository.SetIsActive"
 
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
 	query := `
 		UPDATE users 
 		SET is_active = $1 
 		WHERE id = $2 
-		RETURNING id, name, team_name, is_active
-	`
+		RETURNING ` + userColumns
 
-	var user m.User
-	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &user, query, isActive, userID)
+	var row userRow
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &row, query, isActive, userID)
 	if err != nil {
 		slog.Error("failed to set user active status",
 			"method", method,
@@ -99,5 +134,69 @@ func (r *userRepository) SetIsActive(ctx context.Context, userID string, isActiv
 		return nil, err
 	}
 
+	user := row.toModel()
 	return &user, nil
 }
+
+func (r *userRepository) SetMaxOpenReviews(ctx context.Context, userID string, maxOpenReviews *int) (*m.User, error) {
+	const method = "UserRepository.SetMaxOpenReviews"
+
+	ctx, span := tracing.StartQuery(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET max_open_reviews = $1
+		WHERE id = $2
+		RETURNING ` + userColumns
+
+	var row userRow
+	err := r.getter.DefaultTrOrDB(ctx, r.db).GetContext(ctx, &row, query, maxOpenReviews, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		slog.Error("failed to set user max open reviews",
+			"method", method,
+			"user_id", userID,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	user := row.toModel()
+	return &user, nil
+}
+
+// DeactivateMany deactivates the given users and returns those that were
+// active before the call.
+func (r *userRepository) DeactivateMany(ctx context.Context, userIDs []string) ([]m.User, error) {
+	const method = "UserRepository.DeactivateMany"
+
+	ctx, span := tracing.StartQuery(ctx, method)
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET is_active = FALSE
+		WHERE id = ANY($1) AND is_active
+		RETURNING ` + userColumns
+
+	var rows []userRow
+	err := r.getter.DefaultTrOrDB(ctx, r.db).SelectContext(ctx, &rows, query, pq.Array(userIDs))
+	if err != nil {
+		slog.Error("failed to deactivate users",
+			"method", method,
+			"users_count", len(userIDs),
+			"error", err,
+		)
+		return nil, err
+	}
+
+	users := make([]m.User, len(rows))
+	for i, row := range rows {
+		users[i] = row.toModel()
+	}
+
+	return users, nil
+}
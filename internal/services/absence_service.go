@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/jonx8/pr-review-service/internal/errors"
+	"github.com/jonx8/pr-review-service/internal/ical"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	absenceBatchSize    = 20
+	absencePollInterval = time.Minute
+	absenceReasonLength = 255
+)
+
+type AbsenceService interface {
+	CreateAbsence(ctx context.Context, request m.CreateAbsenceRequest) (*m.Absence, error)
+	GetAbsence(ctx context.Context, absenceID int64) (*m.Absence, error)
+	GetAbsences(ctx context.Context, userID string) ([]m.Absence, error)
+	DeleteAbsence(ctx context.Context, absenceID int64) error
+	ImportAbsences(ctx context.Context, request m.ImportAbsencesRequest, calendar io.Reader) (*m.AbsenceImportReport, error)
+	RunScheduler(ctx context.Context)
+}
+
+type absenceService struct {
+	absenceRepo repo.AbsenceRepository
+	userService UserService
+	prService   PRService
+	trManager   *manager.Manager
+}
+
+func NewAbsenceService(absenceRepo repo.AbsenceRepository, userService UserService, prService PRService, trManager *manager.Manager) AbsenceService {
+	return &absenceService{
+		absenceRepo: absenceRepo,
+		userService: userService,
+		prService:   prService,
+		trManager:   trManager,
+	}
+}
+
+// CreateAbsence records the absence. When it has already begun and
+// ReassignReviews is set, the user's open reviews are handed over right away
+// instead of waiting for the scheduler.
+func (s *absenceService) CreateAbsence(ctx context.Context, request m.CreateAbsenceRequest) (*m.Absence, error) {
+	const method = "AbsenceService.CreateAbsence"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", request.UserID))
+	defer span.End()
+
+	absence := &m.Absence{
+		UserID:          request.UserID,
+		StartsAt:        request.StartsAt,
+		EndsAt:          request.EndsAt,
+		Reason:          request.Reason,
+		ReassignReviews: request.ReassignReviews,
+		ActorID:         u.ActorFromContext(ctx),
+	}
+
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		if _, err := s.userService.GetUser(ctx, request.UserID); err != nil {
+			return err
+		}
+
+		return s.saveAbsences(ctx, []*m.Absence{absence})
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	slog.Info("absence created",
+		"method", method,
+		"absence_id", absence.AbsenceID,
+		"user_id", absence.UserID,
+		"starts_at", absence.StartsAt,
+		"ends_at", absence.EndsAt,
+	)
+
+	return absence, nil
+}
+
+func (s *absenceService) GetAbsence(ctx context.Context, absenceID int64) (*m.Absence, error) {
+	const method = "AbsenceService.GetAbsence"
+
+	absence, err := s.absenceRepo.GetByID(ctx, absenceID)
+	if err != nil {
+		slog.Error("failed to get absence",
+			"method", method,
+			"absence_id", absenceID,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to get absence")
+	}
+
+	if absence == nil {
+		return nil, errors.ErrAbsenceNotFound
+	}
+
+	return absence, nil
+}
+
+func (s *absenceService) GetAbsences(ctx context.Context, userID string) ([]m.Absence, error) {
+	const method = "AbsenceService.GetAbsences"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
+	if _, err := s.userService.GetUser(ctx, userID); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	absences, err := s.absenceRepo.GetByUser(ctx, userID)
+	if err != nil {
+		slog.Error("failed to get absences",
+			"method", method,
+			"user_id", userID,
+			"error", err,
+		)
+		tracing.RecordError(span, err)
+		return nil, errors.WrapInternal(err, "failed to get absences")
+	}
+
+	return absences, nil
+}
+
+// DeleteAbsence cancels the absence, so the user is picked as a reviewer
+// again. Reviews already handed over are not returned.
+func (s *absenceService) DeleteAbsence(ctx context.Context, absenceID int64) error {
+	const method = "AbsenceService.DeleteAbsence"
+
+	ctx, span := tracing.Start(ctx, method, attribute.Int64("absence_id", absenceID))
+	defer span.End()
+
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		if _, err := s.GetAbsence(ctx, absenceID); err != nil {
+			return err
+		}
+
+		if err := s.absenceRepo.Delete(ctx, absenceID); err != nil {
+			slog.Error("failed to delete absence",
+				"method", method,
+				"absence_id", absenceID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to delete absence")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// ImportAbsences creates an absence for every event of an iCalendar file that
+// has not ended yet. Events are matched by UID, so importing an updated
+// calendar again moves the absences instead of duplicating them.
+func (s *absenceService) ImportAbsences(ctx context.Context, request m.ImportAbsencesRequest, calendar io.Reader) (*m.AbsenceImportReport, error) {
+	const method = "AbsenceService.ImportAbsences"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", request.UserID))
+	defer span.End()
+
+	events, err := ical.Parse(calendar)
+	if err != nil {
+		slog.Warn("invalid calendar",
+			"method", method,
+			"user_id", request.UserID,
+			"error", err,
+		)
+		return nil, errors.NewValidation("invalid calendar: " + err.Error())
+	}
+
+	report := &m.AbsenceImportReport{
+		UserID:   request.UserID,
+		Imported: []m.Absence{},
+	}
+
+	now := time.Now()
+	actorID := u.ActorFromContext(ctx)
+	var absences []*m.Absence
+	for _, event := range events {
+		if !event.End.After(now) || !event.End.After(event.Start) {
+			report.Skipped++
+			continue
+		}
+
+		absence := &m.Absence{
+			UserID:          request.UserID,
+			StartsAt:        event.Start,
+			EndsAt:          event.End,
+			ReassignReviews: request.ReassignReviews,
+			ActorID:         actorID,
+		}
+		if event.Summary != "" {
+			reason := truncate(event.Summary, absenceReasonLength)
+			absence.Reason = &reason
+		}
+		if event.UID != "" {
+			uid := event.UID
+			absence.ExternalUID = &uid
+		}
+		absences = append(absences, absence)
+	}
+
+	err = s.trManager.Do(ctx, func(ctx context.Context) error {
+		if _, err := s.userService.GetUser(ctx, request.UserID); err != nil {
+			return err
+		}
+
+		return s.saveAbsences(ctx, absences)
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	for _, absence := range absences {
+		report.Imported = append(report.Imported, *absence)
+	}
+
+	slog.Info("absences imported",
+		"method", method,
+		"user_id", request.UserID,
+		"imported", len(report.Imported),
+		"skipped", report.Skipped,
+	)
+
+	return report, nil
+}
+
+// RunScheduler hands over the open reviews of users whose absences begin,
+// until ctx is canceled.
+func (s *absenceService) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(absencePollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.startPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *absenceService) startPending(ctx context.Context) {
+	const method = "AbsenceService.startPending"
+
+	var afterID int64
+	for ctx.Err() == nil {
+		lastID, processed, err := s.startBatch(ctx, afterID)
+		if err != nil {
+			slog.Error("failed to start absences",
+				"method", method,
+				"error", err,
+			)
+			return
+		}
+
+		if processed < absenceBatchSize {
+			return
+		}
+		afterID = lastID
+	}
+}
+
+// startBatch starts the absences following afterID. Each absence is started in
+// its own transaction, so one that fails is logged and skipped without holding
+// up the others; it is retried on the next run.
+func (s *absenceService) startBatch(ctx context.Context, afterID int64) (int64, int, error) {
+	const method = "AbsenceService.startBatch"
+
+	lastID := afterID
+	for processed := 0; processed < absenceBatchSize; processed++ {
+		var absence *m.Absence
+		err := s.trManager.Do(ctx, func(ctx context.Context) error {
+			absences, err := s.absenceRepo.GetStartingForUpdate(ctx, lastID, 1)
+			if err != nil {
+				return errors.WrapInternal(err, "failed to get starting absences")
+			}
+			if len(absences) == 0 {
+				return nil
+			}
+
+			absence = &absences[0]
+			return s.startAbsence(ctx, absence)
+		})
+
+		if absence == nil {
+			return lastID, processed, err
+		}
+		if err != nil {
+			slog.Error("failed to start absence",
+				"method", method,
+				"absence_id", absence.AbsenceID,
+				"user_id", absence.UserID,
+				"error", err,
+			)
+		}
+		lastID = absence.AbsenceID
+	}
+
+	return lastID, absenceBatchSize, nil
+}
+
+// saveAbsences stores the absences and starts those that have already begun.
+func (s *absenceService) saveAbsences(ctx context.Context, absences []*m.Absence) error {
+	const method = "AbsenceService.saveAbsences"
+
+	now := time.Now()
+	for _, absence := range absences {
+		if err := s.absenceRepo.Save(ctx, absence); err != nil {
+			slog.Error("failed to save absence",
+				"method", method,
+				"user_id", absence.UserID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to save absence")
+		}
+
+		if absence.ReassignReviews && absence.ReviewsReassignedAt == nil && absence.IsActiveAt(now) {
+			if err := s.startAbsence(ctx, absence); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// startAbsence hands the open reviews of the absent user over to other
+// reviewers and marks the absence as handled.
+func (s *absenceService) startAbsence(ctx context.Context, absence *m.Absence) error {
+	const method = "AbsenceService.startAbsence"
+
+	reassigned, unfilled, queued, err := s.prService.ReassignAbsentReviews(ctx, absence.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.absenceRepo.MarkReviewsReassigned(ctx, []int64{absence.AbsenceID}); err != nil {
+		slog.Error("failed to mark absence reviews reassigned",
+			"method", method,
+			"absence_id", absence.AbsenceID,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to mark absence reviews reassigned")
+	}
+
+	now := time.Now()
+	absence.ReviewsReassignedAt = &now
+
+	slog.Info("absence started",
+		"method", method,
+		"absence_id", absence.AbsenceID,
+		"user_id", absence.UserID,
+		"reassigned", reassigned,
+		"no_candidate", len(unfilled),
+		"queued", len(queued),
+	)
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+// fakeAbsenceRepo marks absences handled only when the transaction that did
+// it commits.
+type fakeAbsenceRepo struct {
+	repo.AbsenceRepository
+	absences []m.Absence
+}
+
+func (r *fakeAbsenceRepo) GetStartingForUpdate(_ context.Context, afterID int64, limit int) ([]m.Absence, error) {
+	var absences []m.Absence
+	for _, absence := range r.absences {
+		if absence.AbsenceID > afterID && absence.ReviewsReassignedAt == nil && len(absences) < limit {
+			absences = append(absences, absence)
+		}
+	}
+	return absences, nil
+}
+
+func (r *fakeAbsenceRepo) MarkReviewsReassigned(ctx context.Context, ids []int64) error {
+	stage(ctx, func() {
+		now := time.Now()
+		for i := range r.absences {
+			if slices.Contains(ids, r.absences[i].AbsenceID) {
+				r.absences[i].ReviewsReassignedAt = &now
+			}
+		}
+	})
+	return nil
+}
+
+func (r *fakeAbsenceRepo) pending() []string {
+	var userIDs []string
+	for _, absence := range r.absences {
+		if absence.ReviewsReassignedAt == nil {
+			userIDs = append(userIDs, absence.UserID)
+		}
+	}
+	return userIDs
+}
+
+// fakeAbsentReviews fails to hand over the reviews of the users in failing.
+type fakeAbsentReviews struct {
+	PRService
+	failing []string
+}
+
+func (s *fakeAbsentReviews) ReassignAbsentReviews(_ context.Context, userID string) (int, []m.UnfilledReview, []m.UnfilledReview, error) {
+	if slices.Contains(s.failing, userID) {
+		return 0, nil, nil, errors.New("connection reset")
+	}
+	return 1, nil, nil, nil
+}
+
+func TestStartPendingSkipsFailingAbsence(t *testing.T) {
+	absenceRepo := &fakeAbsenceRepo{}
+	for i, userID := range []string{"u1", "u2", "u3"} {
+		absenceRepo.absences = append(absenceRepo.absences, m.Absence{
+			AbsenceID:       int64(i + 1),
+			UserID:          userID,
+			ReassignReviews: true,
+		})
+	}
+	service := &absenceService{
+		absenceRepo: absenceRepo,
+		prService:   &fakeAbsentReviews{failing: []string{"u2"}},
+		trManager:   newFakeTrManager(),
+	}
+
+	service.startPending(context.Background())
+
+	if pending := absenceRepo.pending(); !slices.Equal(pending, []string{"u2"}) {
+		t.Errorf("absences not started = %v, want only the failing one of u2", pending)
+	}
+}
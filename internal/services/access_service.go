@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+)
+
+// AccessService decides whether the user making a request may act on a
+// particular team, user or PR. API keys and admins are only limited by the
+// scopes of the route, and so are requests without a caller.
+type AccessService interface {
+	// CheckTeam allows team leads to manage their own teams.
+	CheckTeam(ctx context.Context, teamName string) error
+	// CheckUser allows team leads to manage members of their teams.
+	CheckUser(ctx context.Context, userID string) error
+	// CheckAuthor allows members to open PRs as themselves and team leads
+	// to open PRs for members of their teams.
+	CheckAuthor(ctx context.Context, authorID string) error
+	// CheckPR allows members and team leads to act on PRs that belong to
+	// one of their teams.
+	CheckPR(ctx context.Context, prID string) error
+	// CheckReviewer allows members to act only as the given reviewer
+	// themselves and team leads to act on PRs of their teams.
+	CheckReviewer(ctx context.Context, prID string, reviewerID string) error
+	// CheckReviewSubmitter allows members and team leads to submit reviews
+	// only as themselves, so nobody approves on behalf of another reviewer.
+	CheckReviewSubmitter(ctx context.Context, reviewerID string) error
+	// CheckForceMerge allows only admins to merge PRs without the required
+	// approvals.
+	CheckForceMerge(ctx context.Context) error
+}
+
+type accessService struct {
+	prService   PRService
+	userService UserService
+}
+
+func NewAccessService(prService PRService, userService UserService) AccessService {
+	return &accessService{
+		prService:   prService,
+		userService: userService,
+	}
+}
+
+// restrictedUser returns the calling user if their role limits what they may
+// act on, and nil otherwise.
+func restrictedUser(ctx context.Context) *m.Principal {
+	principal := u.PrincipalFromContext(ctx)
+	if principal == nil || !principal.IsUser() || principal.Role == m.RoleAdmin {
+		return nil
+	}
+	return principal
+}
+
+func (s *accessService) CheckTeam(ctx context.Context, teamName string) error {
+	principal := restrictedUser(ctx)
+	if principal == nil {
+		return nil
+	}
+
+	if principal.Role != m.RoleTeamLead || !principal.InTeam(teamName) {
+		return errors.ErrForbidden
+	}
+
+	return nil
+}
+
+func (s *accessService) CheckUser(ctx context.Context, userID string) error {
+	principal := restrictedUser(ctx)
+	if principal == nil {
+		return nil
+	}
+
+	if principal.Role != m.RoleTeamLead {
+		return errors.ErrForbidden
+	}
+
+	return s.checkTeamMember(ctx, principal, userID)
+}
+
+func (s *accessService) CheckAuthor(ctx context.Context, authorID string) error {
+	principal := restrictedUser(ctx)
+	if principal == nil || principal.UserID == authorID {
+		return nil
+	}
+
+	if principal.Role != m.RoleTeamLead {
+		return errors.ErrForbidden
+	}
+
+	return s.checkTeamMember(ctx, principal, authorID)
+}
+
+func (s *accessService) CheckPR(ctx context.Context, prID string) error {
+	principal := restrictedUser(ctx)
+	if principal == nil {
+		return nil
+	}
+
+	return s.checkTeamPR(ctx, principal, prID)
+}
+
+func (s *accessService) CheckReviewer(ctx context.Context, prID string, reviewerID string) error {
+	principal := restrictedUser(ctx)
+	if principal == nil || principal.UserID == reviewerID {
+		return nil
+	}
+
+	if principal.Role != m.RoleTeamLead {
+		return errors.ErrForbidden
+	}
+
+	return s.checkTeamPR(ctx, principal, prID)
+}
+
+func (s *accessService) CheckReviewSubmitter(ctx context.Context, reviewerID string) error {
+	principal := restrictedUser(ctx)
+	if principal == nil || principal.UserID == reviewerID {
+		return nil
+	}
+
+	return errors.ErrForbidden
+}
+
+func (s *accessService) CheckForceMerge(ctx context.Context) error {
+	principal := u.PrincipalFromContext(ctx)
+	if principal == nil || principal.IsAdmin() {
+		return nil
+	}
+
+	return errors.ErrForbidden
+}
+
+func (s *accessService) checkTeamPR(ctx context.Context, principal *m.Principal, prID string) error {
+	pr, err := s.prService.GetPR(ctx, prID)
+	if err != nil {
+		return err
+	}
+
+	if !principal.InTeam(pr.TeamName) {
+		return errors.ErrForbidden
+	}
+
+	return nil
+}
+
+func (s *accessService) checkTeamMember(ctx context.Context, principal *m.Principal, userID string) error {
+	user, err := s.userService.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !slices.ContainsFunc(user.Teams, principal.InTeam) {
+		return errors.ErrForbidden
+	}
+
+	return nil
+}
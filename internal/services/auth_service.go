@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+
+	"github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+)
+
+const (
+	apiKeyPrefix      = "prk_"
+	apiKeyBytes       = 32
+	apiKeyShownPrefix = 12
+)
+
+type AuthService interface {
+	CreateAPIKey(ctx context.Context, request m.CreateAPIKeyRequest) (*m.CreatedAPIKey, error)
+	GetAPIKeys(ctx context.Context) ([]m.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID int64) (*m.APIKey, error)
+	Authenticate(ctx context.Context, token string) (*m.Principal, error)
+}
+
+type authService struct {
+	apiKeyRepo    repo.APIKeyRepository
+	userService   UserService
+	verifier      TokenVerifier
+	bootstrapHash string
+}
+
+// NewAuthService creates the service. A non-empty bootstrapKey is accepted as
+// an admin key, so that the first keys can be created. User tokens are only
+// accepted with a non-nil verifier.
+func NewAuthService(apiKeyRepo repo.APIKeyRepository, userService UserService, verifier TokenVerifier, bootstrapKey string) AuthService {
+	service := &authService{
+		apiKeyRepo:  apiKeyRepo,
+		userService: userService,
+		verifier:    verifier,
+	}
+	if bootstrapKey != "" {
+		service.bootstrapHash = hashAPIKey(bootstrapKey)
+	}
+	return service
+}
+
+func (s *authService) CreateAPIKey(ctx context.Context, request m.CreateAPIKeyRequest) (*m.CreatedAPIKey, error) {
+	const method = "AuthService.CreateAPIKey"
+
+	secret := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.WrapInternal(err, "failed to generate API key")
+	}
+	plain := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	ownerID := u.ActorFromContext(ctx)
+	if request.OwnerID != "" {
+		owner, err := s.userService.GetUser(ctx, request.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+		ownerID = &owner.UserID
+	}
+
+	created := &m.CreatedAPIKey{
+		APIKey: m.APIKey{
+			Name:    request.Name,
+			Prefix:  plain[:apiKeyShownPrefix],
+			Scopes:  request.Scopes,
+			OwnerID: ownerID,
+		},
+		Key: plain,
+	}
+
+	if err := s.apiKeyRepo.Create(ctx, &created.APIKey, hashAPIKey(plain)); err != nil {
+		slog.Error("failed to create API key",
+			"method", method,
+			"name", request.Name,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to create API key")
+	}
+
+	slog.Info("API key created",
+		"method", method,
+		"key_id", created.ID,
+		"name", created.Name,
+		"scopes", created.Scopes,
+	)
+
+	return created, nil
+}
+
+func (s *authService) GetAPIKeys(ctx context.Context) ([]m.APIKey, error) {
+	const method = "AuthService.GetAPIKeys"
+
+	keys, err := s.apiKeyRepo.GetAll(ctx)
+	if err != nil {
+		slog.Error("failed to get API keys",
+			"method", method,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to get API keys")
+	}
+
+	return keys, nil
+}
+
+func (s *authService) RevokeAPIKey(ctx context.Context, keyID int64) (*m.APIKey, error) {
+	const method = "AuthService.RevokeAPIKey"
+
+	key, err := s.apiKeyRepo.Revoke(ctx, keyID)
+	if err != nil {
+		slog.Error("failed to revoke API key",
+			"method", method,
+			"key_id", keyID,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to revoke API key")
+	}
+
+	if key == nil {
+		return nil, errors.ErrAPIKeyNotFound
+	}
+
+	slog.Info("API key revoked",
+		"method", method,
+		"key_id", key.ID,
+		"name", key.Name,
+	)
+
+	return key, nil
+}
+
+// Authenticate resolves a bearer token to the caller. Tokens that look like
+// JWTs are verified as user tokens, anything else is looked up as an API key.
+// Unknown, revoked and invalid credentials are rejected with ErrUnauthorized.
+func (s *authService) Authenticate(ctx context.Context, token string) (*m.Principal, error) {
+	if s.verifier != nil && strings.Count(token, ".") == 2 {
+		return s.authenticateUser(ctx, token)
+	}
+
+	key, err := s.authenticateKey(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.NewKeyPrincipal(key), nil
+}
+
+func (s *authService) authenticateKey(ctx context.Context, key string) (*m.APIKey, error) {
+	const method = "AuthService.authenticateKey"
+
+	keyHash := hashAPIKey(key)
+	if s.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(keyHash), []byte(s.bootstrapHash)) == 1 {
+		return &m.APIKey{Name: "bootstrap", Scopes: []m.Scope{m.ScopeAdmin}}, nil
+	}
+
+	apiKey, err := s.apiKeyRepo.GetByHash(ctx, keyHash)
+	if err != nil {
+		slog.Error("failed to get API key",
+			"method", method,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to get API key")
+	}
+
+	if apiKey == nil || apiKey.RevokedAt != nil {
+		return nil, errors.ErrUnauthorized
+	}
+
+	return apiKey, nil
+}
+
+func (s *authService) authenticateUser(ctx context.Context, token string) (*m.Principal, error) {
+	const method = "AuthService.authenticateUser"
+
+	claims, err := s.verifier.Verify(token)
+	if err != nil {
+		slog.Warn("rejected user token",
+			"method", method,
+			"error", err,
+		)
+		return nil, errors.ErrInvalidUserToken
+	}
+
+	user, err := s.userService.GetUser(ctx, claims.UserID)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
+			slog.Warn("token subject is not a known user",
+				"method", method,
+				"user_id", claims.UserID,
+			)
+			return nil, errors.ErrInvalidUserToken
+		}
+		return nil, err
+	}
+
+	return m.NewUserPrincipal(user, claims.Role), nil
+}
+
+// hashAPIKey returns the hex SHA-256 of the key. Keys are random and long, so
+// a fast hash is enough to keep stored values useless if leaked.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+const maxPRNameLength = 255
+
+type ForgeService interface {
+	HandlePREvent(ctx context.Context, event m.ForgePREvent) (*m.PullRequest, error)
+	SetUserMapping(ctx context.Context, mapping *m.ForgeUserMapping) (*m.ForgeUserMapping, error)
+}
+
+type forgeService struct {
+	forgeRepo   repo.ForgeRepository
+	prService   PRService
+	userService UserService
+	trManager   *manager.Manager
+}
+
+func NewForgeService(forgeRepo repo.ForgeRepository, prService PRService, userService UserService, trManager *manager.Manager) ForgeService {
+	return &forgeService{
+		forgeRepo:   forgeRepo,
+		prService:   prService,
+		userService: userService,
+		trManager:   trManager,
+	}
+}
+
+func (s *forgeService) HandlePREvent(ctx context.Context, event m.ForgePREvent) (*m.PullRequest, error) {
+	const method = "ForgeService.HandlePREvent"
+
+	prID := forgePRID(event.Forge, event.ExternalID)
+
+	slog.Info("handling forge PR event",
+		"method", method,
+		"forge", event.Forge,
+		"action", event.Action,
+		"delivery_id", event.DeliveryID,
+		"pr_id", prID,
+	)
+
+	var resultPR *m.PullRequest
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		if event.DeliveryID != "" {
+			firstDelivery, err := s.forgeRepo.RegisterDelivery(ctx, event.Forge, event.DeliveryID)
+			if err != nil {
+				return errors.WrapInternal(err, "failed to register forge delivery")
+			}
+			if !firstDelivery {
+				slog.Warn("forge event already delivered",
+					"method", method,
+					"forge", event.Forge,
+					"delivery_id", event.DeliveryID,
+				)
+				resultPR, err = s.prService.GetPR(ctx, prID)
+				return err
+			}
+		}
+
+		pr, err := s.applyPREvent(ctx, prID, event)
+		if err != nil {
+			return err
+		}
+
+		resultPR = pr
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resultPR, nil
+}
+
+func (s *forgeService) applyPREvent(ctx context.Context, prID string, event m.ForgePREvent) (*m.PullRequest, error) {
+	switch event.Action {
+	case m.ForgeActionOpened:
+		return s.openPR(ctx, prID, event)
+	case m.ForgeActionUpdated:
+		return s.updatePR(ctx, prID, event)
+	case m.ForgeActionReady:
+		return s.prService.MarkReady(ctx, m.MarkReadyRequest{PullRequestID: prID})
+	case m.ForgeActionMerged:
+		// The forge has already merged the PR, so approvals cannot block it here
+		return s.prService.MergePR(ctx, prID, true)
+	case m.ForgeActionClosed:
+		return s.prService.ClosePR(ctx, prID)
+	case m.ForgeActionReopened:
+		return s.prService.ReopenPR(ctx, prID)
+	default:
+		return nil, errors.NewValidation(fmt.Sprintf("unsupported forge action %s", event.Action))
+	}
+}
+
+func (s *forgeService) updatePR(ctx context.Context, prID string, event m.ForgePREvent) (*m.PullRequest, error) {
+	pr, err := s.prService.UpdateTitle(ctx, prID, truncate(event.Title, maxPRNameLength))
+	if err != nil {
+		return nil, err
+	}
+
+	if pr.Status == m.StatusDraft && !event.Draft {
+		return s.prService.MarkReady(ctx, m.MarkReadyRequest{PullRequestID: prID})
+	}
+
+	return pr, nil
+}
+
+func (s *forgeService) openPR(ctx context.Context, prID string, event m.ForgePREvent) (*m.PullRequest, error) {
+	const method = "ForgeService.openPR"
+
+	authorID, err := s.resolveAuthor(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := s.prService.CreatePR(ctx, m.CreatePRRequest{
+		PullRequestID:   prID,
+		PullRequestName: truncate(event.Title, maxPRNameLength),
+		AuthorID:        authorID,
+		Draft:           event.Draft,
+	})
+	if err == errors.ErrPRExists {
+		slog.Warn("forge PR already exists",
+			"method", method,
+			"pr_id", prID,
+		)
+		return s.prService.GetPR(ctx, prID)
+	}
+
+	return pr, err
+}
+
+// resolveAuthor maps the PR author to a user, by the forge user id when the
+// event carries one and by login otherwise.
+func (s *forgeService) resolveAuthor(ctx context.Context, event m.ForgePREvent) (string, error) {
+	if event.AuthorExternalID != 0 {
+		return s.resolveExternalUser(ctx, event.Forge, event.AuthorExternalID)
+	}
+	return s.resolveUser(ctx, event.Forge, event.AuthorLogin)
+}
+
+func (s *forgeService) resolveExternalUser(ctx context.Context, forge m.Forge, externalUserID int64) (string, error) {
+	const method = "ForgeService.resolveExternalUser"
+
+	userID, err := s.forgeRepo.GetUserIDByExternalID(ctx, forge, externalUserID)
+	if err != nil {
+		slog.Error("failed to resolve forge user id",
+			"method", method,
+			"forge", forge,
+			"external_user_id", externalUserID,
+			"error", err,
+		)
+		return "", errors.WrapInternal(err, "failed to resolve forge user id")
+	}
+
+	if userID == nil {
+		slog.Warn("forge user id is not mapped to a user",
+			"method", method,
+			"forge", forge,
+			"external_user_id", externalUserID,
+		)
+		return "", errors.NewNotFound(fmt.Sprintf("no user mapped to %s user id %d", forge, externalUserID))
+	}
+
+	return *userID, nil
+}
+
+func (s *forgeService) resolveUser(ctx context.Context, forge m.Forge, login string) (string, error) {
+	const method = "ForgeService.resolveUser"
+
+	userID, err := s.forgeRepo.GetUserIDByLogin(ctx, forge, login)
+	if err != nil {
+		slog.Error("failed to resolve forge login",
+			"method", method,
+			"forge", forge,
+			"login", login,
+			"error", err,
+		)
+		return "", errors.WrapInternal(err, "failed to resolve forge login")
+	}
+
+	if userID == nil {
+		slog.Warn("forge login is not mapped to a user",
+			"method", method,
+			"forge", forge,
+			"login", login,
+		)
+		return "", errors.NewNotFound(fmt.Sprintf("no user mapped to %s login %s", forge, login))
+	}
+
+	return *userID, nil
+}
+
+func (s *forgeService) SetUserMapping(ctx context.Context, mapping *m.ForgeUserMapping) (*m.ForgeUserMapping, error) {
+	const method = "ForgeService.SetUserMapping"
+
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		if _, err := s.userService.GetUser(ctx, mapping.UserID); err != nil {
+			return err
+		}
+
+		if err := s.forgeRepo.UpsertUserMapping(ctx, mapping); err != nil {
+			slog.Error("failed to save user mapping",
+				"method", method,
+				"forge", mapping.Forge,
+				"login", mapping.Login,
+				"user_id", mapping.UserID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to save user mapping")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+// forgePRID builds a stable PR id from the forge wide unique PR id, so that
+// redelivered events resolve to the same PR.
+func forgePRID(forge m.Forge, externalID int64) string {
+	return fmt.Sprintf("%s-%d", forge, externalID)
+}
+
+func truncate(value string, maxLength int) string {
+	runes := []rune(value)
+	if len(runes) <= maxLength {
+		return value
+	}
+	return string(runes[:maxLength])
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+const (
+	deliveriesPageSize     = 100
+	deliveryBatchSize      = 20
+	deliveryPollInterval   = 5 * time.Second
+	deliveryTimeout        = 10 * time.Second
+	deliveryLease          = time.Minute
+	deliveryMaxAttempts    = 8
+	deliveryInitialBackoff = 10 * time.Second
+	deliveryMaxBackoff     = time.Hour
+)
+
+// EventPublisher accepts domain events for delivery to external subscribers.
+type EventPublisher interface {
+	Publish(ctx context.Context, events ...m.Event) error
+}
+
+type NotificationService interface {
+	EventPublisher
+	CreateSubscription(ctx context.Context, request m.CreateSubscriptionRequest) (*m.Subscription, error)
+	GetSubscriptions(ctx context.Context) ([]m.Subscription, error)
+	DeactivateSubscription(ctx context.Context, subscriptionID int64) (*m.Subscription, error)
+	GetDeliveries(ctx context.Context, subscriptionID int64) ([]m.WebhookDelivery, error)
+	RunDispatcher(ctx context.Context)
+}
+
+type notificationService struct {
+	subscriptionRepo repo.SubscriptionRepository
+	trManager        *manager.Manager
+	httpClient       *http.Client
+	wakeup           chan struct{}
+}
+
+func NewNotificationService(subscriptionRepo repo.SubscriptionRepository, trManager *manager.Manager) NotificationService {
+	return &notificationService{
+		subscriptionRepo: subscriptionRepo,
+		trManager:        trManager,
+		httpClient:       &http.Client{Timeout: deliveryTimeout},
+		wakeup:           make(chan struct{}, 1),
+	}
+}
+
+func (s *notificationService) CreateSubscription(ctx context.Context, request m.CreateSubscriptionRequest) (*m.Subscription, error) {
+	const method = "NotificationService.CreateSubscription"
+
+	subscription := &m.Subscription{
+		URL:      request.URL,
+		Secret:   request.Secret,
+		Events:   request.Events,
+		IsActive: true,
+	}
+
+	if err := s.subscriptionRepo.Create(ctx, subscription); err != nil {
+		slog.Error("failed to create subscription",
+			"method", method,
+			"url", request.URL,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to create subscription")
+	}
+
+	return subscription, nil
+}
+
+func (s *notificationService) GetSubscriptions(ctx context.Context) ([]m.Subscription, error) {
+	const method = "NotificationService.GetSubscriptions"
+
+	subscriptions, err := s.subscriptionRepo.GetAll(ctx)
+	if err != nil {
+		slog.Error("failed to get subscriptions",
+			"method", method,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to get subscriptions")
+	}
+
+	return subscriptions, nil
+}
+
+// DeactivateSubscription stops sending events to the subscriber. Deliveries
+// that are still pending are marked FAILED, while the delivery history is
+// kept.
+func (s *notificationService) DeactivateSubscription(ctx context.Context, subscriptionID int64) (*m.Subscription, error) {
+	const method = "NotificationService.DeactivateSubscription"
+
+	var subscription *m.Subscription
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		var err error
+		subscription, err = s.subscriptionRepo.Deactivate(ctx, subscriptionID)
+		if err != nil {
+			slog.Error("failed to deactivate subscription",
+				"method", method,
+				"subscription_id", subscriptionID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to deactivate subscription")
+		}
+		if subscription == nil {
+			return errors.ErrSubscriptionNotFound
+		}
+
+		if err := s.subscriptionRepo.FailPendingDeliveries(ctx, subscriptionID, "subscription deactivated"); err != nil {
+			slog.Error("failed to cancel pending deliveries",
+				"method", method,
+				"subscription_id", subscriptionID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to cancel pending deliveries")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("subscription deactivated",
+		"method", method,
+		"subscription_id", subscriptionID,
+	)
+
+	return subscription, nil
+}
+
+func (s *notificationService) GetDeliveries(ctx context.Context, subscriptionID int64) ([]m.WebhookDelivery, error) {
+	const method = "NotificationService.GetDeliveries"
+
+	exists, err := s.subscriptionRepo.ExistsByID(ctx, subscriptionID)
+	if err != nil {
+		slog.Error("failed to check subscription existence",
+			"method", method,
+			"subscription_id", subscriptionID,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to check subscription existence")
+	}
+	if !exists {
+		return nil, errors.ErrSubscriptionNotFound
+	}
+
+	deliveries, err := s.subscriptionRepo.GetDeliveries(ctx, subscriptionID, deliveriesPageSize)
+	if err != nil {
+		slog.Error("failed to get deliveries",
+			"method", method,
+			"subscription_id", subscriptionID,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to get deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// Publish queues a delivery of each event for every active subscription
+// interested in it. Sending happens asynchronously in RunDispatcher.
+func (s *notificationService) Publish(ctx context.Context, events ...m.Event) error {
+	const method = "NotificationService.Publish"
+
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		for _, event := range events {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+
+			subscriptions, err := s.subscriptionRepo.GetActiveByEvent(ctx, event.Type)
+			if err != nil {
+				return err
+			}
+
+			deliveries := make([]m.WebhookDelivery, len(subscriptions))
+			for i, subscription := range subscriptions {
+				deliveries[i] = m.WebhookDelivery{
+					SubscriptionID: subscription.ID,
+					EventType:      event.Type,
+					Payload:        payload,
+					Status:         m.DeliveryPending,
+				}
+			}
+
+			if err := s.subscriptionRepo.CreateDeliveries(ctx, deliveries); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("failed to publish events",
+			"method", method,
+			"events_count", len(events),
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to publish events")
+	}
+
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// RunDispatcher sends pending deliveries until ctx is canceled. Failed
+// deliveries are retried with exponential backoff.
+func (s *notificationService) RunDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wakeup:
+		}
+	}
+}
+
+func (s *notificationService) dispatchDue(ctx context.Context) {
+	const method = "NotificationService.dispatchDue"
+
+	for ctx.Err() == nil {
+		deliveries, err := s.subscriptionRepo.ClaimDueDeliveries(ctx, deliveryBatchSize, deliveryLease)
+		if err != nil {
+			slog.Error("failed to claim due deliveries",
+				"method", method,
+				"error", err,
+			)
+			return
+		}
+
+		for i := range deliveries {
+			s.deliver(ctx, &deliveries[i])
+		}
+
+		if len(deliveries) < deliveryBatchSize {
+			return
+		}
+	}
+}
+
+func (s *notificationService) deliver(ctx context.Context, due *m.DueDelivery) {
+	const method = "NotificationService.deliver"
+
+	delivery := &due.WebhookDelivery
+	delivery.Attempts++
+
+	statusCode, err := s.send(ctx, due)
+	if statusCode != 0 {
+		delivery.ResponseStatus = &statusCode
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = m.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = nil
+	case delivery.Attempts >= deliveryMaxAttempts:
+		lastError := err.Error()
+		delivery.Status = m.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = &lastError
+	default:
+		lastError := err.Error()
+		nextAttemptAt := now.Add(deliveryBackoff(delivery.Attempts))
+		delivery.NextAttemptAt = &nextAttemptAt
+		delivery.LastError = &lastError
+	}
+
+	if err != nil {
+		slog.Warn("webhook delivery attempt failed",
+			"method", method,
+			"delivery_id", delivery.ID,
+			"subscription_id", delivery.SubscriptionID,
+			"attempts", delivery.Attempts,
+			"error", err,
+		)
+	}
+
+	if err := s.subscriptionRepo.UpdateDelivery(ctx, delivery); err != nil {
+		slog.Error("failed to save delivery result",
+			"method", method,
+			"delivery_id", delivery.ID,
+			"error", err,
+		)
+	}
+}
+
+func (s *notificationService) send(ctx context.Context, due *m.DueDelivery) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, due.URL, bytes.NewReader(due.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(due.Secret))
+	mac.Write(due.Payload)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Webhook-Event", string(due.EventType))
+	request.Header.Set("X-Webhook-Delivery", strconv.FormatInt(due.ID, 10))
+	request.Header.Set("X-Webhook-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("unexpected response status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
+
+func deliveryBackoff(attempts int) time.Duration {
+	backoff := deliveryInitialBackoff << (attempts - 1)
+	if backoff <= 0 || backoff > deliveryMaxBackoff {
+		return deliveryMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+// fakeDeliveryRepo keeps the last saved state of each delivery.
+type fakeDeliveryRepo struct {
+	repo.SubscriptionRepository
+	saved map[int64]m.WebhookDelivery
+}
+
+func (r *fakeDeliveryRepo) UpdateDelivery(_ context.Context, delivery *m.WebhookDelivery) error {
+	r.saved[delivery.ID] = *delivery
+	return nil
+}
+
+func newTestNotificationService() (*notificationService, *fakeDeliveryRepo) {
+	deliveryRepo := &fakeDeliveryRepo{saved: make(map[int64]m.WebhookDelivery)}
+	return &notificationService{
+		subscriptionRepo: deliveryRepo,
+		httpClient:       &http.Client{Timeout: time.Second},
+	}, deliveryRepo
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 7, want: 640 * time.Second},
+		{attempts: 9, want: 2560 * time.Second},
+		{attempts: 10, want: time.Hour},
+		{attempts: 40, want: time.Hour},
+		{attempts: 70, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := deliveryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("deliveryBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNotificationSendSignsPayload(t *testing.T) {
+	const secret = "7f3c1a9e5b2d4f60"
+	payload := []byte(`{"type":"pr.merged","occurred_at":"2025-10-24T12:34:56Z","data":{"pull_request_id":"pr-1001"}}`)
+
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service, _ := newTestNotificationService()
+	status, err := service.send(context.Background(), &m.DueDelivery{
+		WebhookDelivery: m.WebhookDelivery{ID: 42, EventType: m.EventPRMerged, Payload: payload},
+		URL:             server.URL,
+		Secret:          secret,
+	})
+	if err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("send() status = %d, want %d", status, http.StatusNoContent)
+	}
+
+	if string(receivedBody) != string(payload) {
+		t.Errorf("body = %s, want %s", receivedBody, payload)
+	}
+	if got := received.Header.Get("X-Webhook-Event"); got != "pr.merged" {
+		t.Errorf("X-Webhook-Event = %q, want pr.merged", got)
+	}
+	if got := received.Header.Get("X-Webhook-Delivery"); got != "42" {
+		t.Errorf("X-Webhook-Delivery = %q, want 42", got)
+	}
+
+	// The subscriber verifies the signature the way the docs describe it
+	signature := received.Header.Get("X-Webhook-Signature-256")
+	digest, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if !strings.HasPrefix(signature, "sha256=") || err != nil {
+		t.Fatalf("X-Webhook-Signature-256 = %q, want sha256=<hex>", signature)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(receivedBody)
+	if !hmac.Equal(digest, mac.Sum(nil)) {
+		t.Errorf("signature %q does not match HMAC-SHA256 of the body", signature)
+	}
+
+	other := hmac.New(sha256.New, []byte("another-secret-16"))
+	other.Write(receivedBody)
+	if hmac.Equal(digest, other.Sum(nil)) {
+		t.Error("signature matches a different secret")
+	}
+}
+
+func TestNotificationDeliverRetriesWithBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	service, deliveryRepo := newTestNotificationService()
+	due := &m.DueDelivery{
+		WebhookDelivery: m.WebhookDelivery{ID: 7, Status: m.DeliveryPending, Payload: []byte(`{}`)},
+		URL:             server.URL,
+		Secret:          "7f3c1a9e5b2d4f60",
+	}
+
+	for attempt := 1; attempt < deliveryMaxAttempts; attempt++ {
+		before := time.Now()
+		service.deliver(context.Background(), due)
+		saved := deliveryRepo.saved[7]
+
+		if saved.Status != m.DeliveryPending || saved.Attempts != attempt {
+			t.Fatalf("attempt %d: status = %s, attempts = %d, want PENDING and %d", attempt, saved.Status, saved.Attempts, attempt)
+		}
+		if saved.ResponseStatus == nil || *saved.ResponseStatus != http.StatusInternalServerError {
+			t.Errorf("attempt %d: response status = %v, want 500", attempt, saved.ResponseStatus)
+		}
+		if saved.LastError == nil {
+			t.Errorf("attempt %d: last error is not recorded", attempt)
+		}
+
+		backoff := deliveryBackoff(attempt)
+		if saved.NextAttemptAt == nil || saved.NextAttemptAt.Before(before.Add(backoff)) || saved.NextAttemptAt.After(time.Now().Add(backoff)) {
+			t.Errorf("attempt %d: next attempt at %v, want %s from now", attempt, saved.NextAttemptAt, backoff)
+		}
+	}
+
+	service.deliver(context.Background(), due)
+	saved := deliveryRepo.saved[7]
+	if saved.Status != m.DeliveryFailed || saved.Attempts != deliveryMaxAttempts || saved.NextAttemptAt != nil {
+		t.Errorf("last attempt: status = %s, attempts = %d, next attempt at %v, want FAILED after %d attempts and no retry",
+			saved.Status, saved.Attempts, saved.NextAttemptAt, deliveryMaxAttempts)
+	}
+}
+
+func TestNotificationDeliverSucceedsAfterFailure(t *testing.T) {
+	var responseStatus atomic.Int32
+	responseStatus.Store(http.StatusBadGateway)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(int(responseStatus.Load()))
+	}))
+	defer server.Close()
+
+	service, deliveryRepo := newTestNotificationService()
+	due := &m.DueDelivery{
+		WebhookDelivery: m.WebhookDelivery{ID: 8, Status: m.DeliveryPending, Payload: []byte(`{}`)},
+		URL:             server.URL,
+		Secret:          "7f3c1a9e5b2d4f60",
+	}
+
+	service.deliver(context.Background(), due)
+	responseStatus.Store(http.StatusOK)
+	service.deliver(context.Background(), due)
+
+	saved := deliveryRepo.saved[8]
+	if saved.Status != m.DeliveryDelivered || saved.Attempts != 2 {
+		t.Fatalf("status = %s, attempts = %d, want DELIVERED after 2 attempts", saved.Status, saved.Attempts)
+	}
+	if saved.DeliveredAt == nil || saved.NextAttemptAt != nil || saved.LastError != nil {
+		t.Errorf("delivered_at = %v, next_attempt_at = %v, last_error = %v, want only delivered_at set",
+			saved.DeliveredAt, saved.NextAttemptAt, saved.LastError)
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	"github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+const (
+	outboxBatchSize    = 50
+	outboxPollInterval = time.Second
+)
+
+// OutboxService records events in the caller's transaction and relays them
+// to the downstream publisher once they are committed.
+type OutboxService interface {
+	EventPublisher
+	RunDispatcher(ctx context.Context)
+}
+
+type outboxService struct {
+	outboxRepo repo.OutboxRepository
+	publisher  EventPublisher
+	trManager  *manager.Manager
+}
+
+func NewOutboxService(outboxRepo repo.OutboxRepository, publisher EventPublisher, trManager *manager.Manager) OutboxService {
+	return &outboxService{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		trManager:  trManager,
+	}
+}
+
+// Publish stores the events in the outbox. It must be called inside the
+// transaction that makes the change, so that both are committed together.
+func (s *outboxService) Publish(ctx context.Context, events ...m.Event) error {
+	const method = "OutboxService.Publish"
+
+	messages := make([]m.OutboxMessage, len(events))
+	for i, event := range events {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return errors.WrapInternal(err, "failed to encode event")
+		}
+
+		messages[i] = m.OutboxMessage{
+			EventType:  event.Type,
+			Payload:    data,
+			OccurredAt: event.OccurredAt,
+		}
+	}
+
+	if err := s.outboxRepo.Add(ctx, messages); err != nil {
+		slog.Error("failed to store events in outbox",
+			"method", method,
+			"events_count", len(events),
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to store events in outbox")
+	}
+
+	return nil
+}
+
+// RunDispatcher relays committed outbox messages until ctx is canceled. A
+// message is marked published in the same transaction that hands it over, so
+// a crash before commit leaves it pending and it is relayed again.
+func (s *outboxService) RunDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.relayPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *outboxService) relayPending(ctx context.Context) {
+	const method = "OutboxService.relayPending"
+
+	for ctx.Err() == nil {
+		relayed, err := s.relayBatch(ctx)
+		if err != nil {
+			slog.Error("failed to relay outbox messages",
+				"method", method,
+				"error", err,
+			)
+			return
+		}
+
+		if relayed < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (s *outboxService) relayBatch(ctx context.Context) (int, error) {
+	relayed := 0
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		messages, err := s.outboxRepo.GetPendingForUpdate(ctx, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(messages))
+		events := make([]m.Event, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+			events[i] = m.Event{
+				Type:       message.EventType,
+				OccurredAt: message.OccurredAt,
+				Data:       message.Payload,
+			}
+		}
+
+		if err := s.publisher.Publish(ctx, events...); err != nil {
+			return err
+		}
+
+		relayed = len(messages)
+		return s.outboxRepo.MarkPublished(ctx, ids)
+	})
+
+	return relayed, err
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/avito-tech/go-transaction-manager/trm/v2"
+	trmcontext "github.com/avito-tech/go-transaction-manager/trm/v2/context"
+	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+// fakeTransaction applies the writes staged by the fakes only on commit, the
+// way a database transaction does.
+type fakeTransaction struct {
+	closed chan struct{}
+	staged []func()
+}
+
+func newFakeTrManager() *manager.Manager {
+	return manager.Must(func(ctx context.Context, _ trm.Settings) (context.Context, trm.Transaction, error) {
+		return ctx, &fakeTransaction{closed: make(chan struct{})}, nil
+	})
+}
+
+// stage runs write on commit of the transaction in ctx, or right away
+// outside of a transaction.
+func stage(ctx context.Context, write func()) {
+	tx, ok := trmcontext.DefaultManager.Default(ctx).(*fakeTransaction)
+	if !ok {
+		write()
+		return
+	}
+	tx.staged = append(tx.staged, write)
+}
+
+func (t *fakeTransaction) Transaction() any { return t }
+
+func (t *fakeTransaction) Commit(context.Context) error {
+	for _, write := range t.staged {
+		write()
+	}
+	close(t.closed)
+	return nil
+}
+
+func (t *fakeTransaction) Rollback(context.Context) error {
+	t.staged = nil
+	close(t.closed)
+	return nil
+}
+
+func (t *fakeTransaction) IsActive() bool {
+	select {
+	case <-t.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+func (t *fakeTransaction) Closed() <-chan struct{} { return t.closed }
+
+// fakeOutboxRepo is an outbox table: rows become visible and are marked
+// published only when the transaction that wrote them commits.
+type fakeOutboxRepo struct {
+	repo.OutboxRepository
+	messages       []m.OutboxMessage
+	markPublishErr error
+}
+
+func (r *fakeOutboxRepo) Add(ctx context.Context, messages []m.OutboxMessage) error {
+	stage(ctx, func() {
+		for _, message := range messages {
+			message.ID = int64(len(r.messages) + 1)
+			r.messages = append(r.messages, message)
+		}
+	})
+	return nil
+}
+
+func (r *fakeOutboxRepo) GetPendingForUpdate(_ context.Context, limit int) ([]m.OutboxMessage, error) {
+	var pending []m.OutboxMessage
+	for _, message := range r.messages {
+		if message.PublishedAt == nil && len(pending) < limit {
+			pending = append(pending, message)
+		}
+	}
+	return pending, nil
+}
+
+func (r *fakeOutboxRepo) MarkPublished(ctx context.Context, ids []int64) error {
+	if r.markPublishErr != nil {
+		return r.markPublishErr
+	}
+	stage(ctx, func() {
+		now := time.Now()
+		for i := range r.messages {
+			if slices.Contains(ids, r.messages[i].ID) {
+				r.messages[i].PublishedAt = &now
+			}
+		}
+	})
+	return nil
+}
+
+func (r *fakeOutboxRepo) pending() int {
+	count := 0
+	for _, message := range r.messages {
+		if message.PublishedAt == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// fakeDeliveryQueue stands in for the notification service, which queues
+// deliveries in the relay's transaction. It fails or crashes after handing
+// over failAfter events of a call, once.
+type fakeDeliveryQueue struct {
+	delivered []string
+	failAfter int
+	crash     bool
+}
+
+var errPublisherUnavailable = errors.New("publisher unavailable")
+
+func (q *fakeDeliveryQueue) Publish(ctx context.Context, events ...m.Event) error {
+	for i, event := range events {
+		if q.failAfter >= 0 && i == q.failAfter {
+			q.failAfter = -1
+			if q.crash {
+				panic("process killed")
+			}
+			return errPublisherUnavailable
+		}
+
+		var data struct {
+			PullRequestID string `json:"pull_request_id"`
+		}
+		if err := json.Unmarshal(event.Data.(json.RawMessage), &data); err != nil {
+			return err
+		}
+		stage(ctx, func() {
+			q.delivered = append(q.delivered, data.PullRequestID)
+		})
+	}
+	return nil
+}
+
+// commitEvents stores the events the way a service does, in the transaction
+// of the state change, and commits it.
+func commitEvents(t *testing.T, outbox OutboxService, trManager *manager.Manager, count int) []string {
+	t.Helper()
+
+	var prIDs []string
+	err := trManager.Do(context.Background(), func(ctx context.Context) error {
+		for i := range count {
+			pr := &m.PullRequest{PullRequestID: fmt.Sprintf("pr-%d", i+1)}
+			prIDs = append(prIDs, pr.PullRequestID)
+			if err := outbox.Publish(ctx, m.NewEvent(m.EventPRCreated, pr)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("commit events: %v", err)
+	}
+	return prIDs
+}
+
+func TestOutboxRelaysOnlyCommittedEvents(t *testing.T) {
+	trManager := newFakeTrManager()
+	outboxRepo := &fakeOutboxRepo{}
+	queue := &fakeDeliveryQueue{failAfter: -1}
+	outbox := NewOutboxService(outboxRepo, queue, trManager).(*outboxService)
+
+	err := trManager.Do(context.Background(), func(ctx context.Context) error {
+		if err := outbox.Publish(ctx, m.NewEvent(m.EventPRCreated, &m.PullRequest{PullRequestID: "pr-rolled-back"})); err != nil {
+			return err
+		}
+		return errors.New("state change failed")
+	})
+	if err == nil {
+		t.Fatal("state change unexpectedly committed")
+	}
+
+	relayed, err := outbox.relayBatch(context.Background())
+	if err != nil || relayed != 0 {
+		t.Fatalf("relayBatch() = %d, %v, want 0, nil", relayed, err)
+	}
+	if len(queue.delivered) != 0 {
+		t.Errorf("delivered = %v, want nothing from a rolled back change", queue.delivered)
+	}
+}
+
+// TestOutboxRelayRecoversFromFailures commits state changes together with
+// their events and lets the relay of the batch fail between the commit and
+// the hand-over. Every event has to be delivered exactly once in the end.
+func TestOutboxRelayRecoversFromFailures(t *testing.T) {
+	tests := []struct {
+		name           string
+		failAfter      int
+		crash          bool
+		markPublishErr error
+	}{
+		{
+			name:      "publisher fails partway through the batch",
+			failAfter: 2,
+		},
+		{
+			name:      "process crashes partway through the batch",
+			failAfter: 3,
+			crash:     true,
+		},
+		{
+			name:      "publisher fails on the first event",
+			failAfter: 0,
+		},
+		{
+			name:           "marking the batch published fails after the hand-over",
+			failAfter:      -1,
+			markPublishErr: errors.New("connection reset"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trManager := newFakeTrManager()
+			outboxRepo := &fakeOutboxRepo{markPublishErr: tt.markPublishErr}
+			queue := &fakeDeliveryQueue{failAfter: tt.failAfter, crash: tt.crash}
+			outbox := NewOutboxService(outboxRepo, queue, trManager).(*outboxService)
+
+			prIDs := commitEvents(t, outbox, trManager, 5)
+
+			err := relayCrashing(outbox)
+			if err == nil {
+				t.Fatal("first relay unexpectedly succeeded")
+			}
+			if len(queue.delivered) != 0 {
+				t.Errorf("delivered after failed relay = %v, want nothing", queue.delivered)
+			}
+			if pending := outboxRepo.pending(); pending != len(prIDs) {
+				t.Errorf("pending after failed relay = %d, want %d", pending, len(prIDs))
+			}
+
+			// A restarted dispatcher picks the batch up again
+			outboxRepo.markPublishErr = nil
+			restarted := NewOutboxService(outboxRepo, queue, trManager).(*outboxService)
+			relayed, err := restarted.relayBatch(context.Background())
+			if err != nil || relayed != len(prIDs) {
+				t.Fatalf("relay after restart = %d, %v, want %d, nil", relayed, err, len(prIDs))
+			}
+
+			relayed, err = restarted.relayBatch(context.Background())
+			if err != nil || relayed != 0 {
+				t.Fatalf("relay of an empty outbox = %d, %v, want 0, nil", relayed, err)
+			}
+
+			if !slices.Equal(queue.delivered, prIDs) {
+				t.Errorf("delivered = %v, want each of %v exactly once", queue.delivered, prIDs)
+			}
+			if pending := outboxRepo.pending(); pending != 0 {
+				t.Errorf("pending = %d, want 0", pending)
+			}
+		})
+	}
+}
+
+// relayCrashing runs one relay and turns a crash of the process into an error.
+func relayCrashing(outbox *outboxService) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("relay crashed: %v", p)
+		}
+	}()
+
+	_, err = outbox.relayBatch(context.Background())
+	return err
+}
+
+func TestOutboxRelaysLargeBacklogInBatches(t *testing.T) {
+	trManager := newFakeTrManager()
+	outboxRepo := &fakeOutboxRepo{}
+	queue := &fakeDeliveryQueue{failAfter: -1}
+	outbox := NewOutboxService(outboxRepo, queue, trManager).(*outboxService)
+
+	prIDs := commitEvents(t, outbox, trManager, outboxBatchSize*2+3)
+
+	outbox.relayPending(context.Background())
+
+	if !slices.Equal(queue.delivered, prIDs) {
+		t.Errorf("delivered %d events, want each of %d exactly once in order", len(queue.delivered), len(prIDs))
+	}
+}
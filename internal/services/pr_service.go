@@ -2,38 +2,59 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
-	"math/rand"
 	"slices"
+	"strconv"
 	"time"
 
 	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 	"github.com/jonx8/pr-review-service/internal/errors"
+	"github.com/jonx8/pr-review-service/internal/metrics"
 	m "github.com/jonx8/pr-review-service/internal/models"
 	repo "github.com/jonx8/pr-review-service/internal/repositories"
+	"github.com/jonx8/pr-review-service/internal/tracing"
 	u "github.com/jonx8/pr-review-service/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type PRService interface {
 	GetPR(ctx context.Context, prID string) (*m.PullRequest, error)
 	CreatePR(ctx context.Context, request m.CreatePRRequest) (*m.PullRequest, error)
-	MergePR(ctx context.Context, prID string) (*m.PullRequest, error)
+	MergePR(ctx context.Context, prID string, force bool) (*m.PullRequest, error)
 	ReassignReviewer(ctx context.Context, prID string, oldUserID string) (resultPR *m.PullRequest, newReviewerID *string, retErr error)
 	GetPRByReviewer(ctx context.Context, userID string) ([]m.PullRequestShort, error)
+	SubmitReview(ctx context.Context, request m.SubmitReviewRequest) (*m.PullRequest, error)
+	ClosePR(ctx context.Context, prID string) (*m.PullRequest, error)
+	ReopenPR(ctx context.Context, prID string) (*m.PullRequest, error)
+	MarkReady(ctx context.Context, request m.MarkReadyRequest) (*m.PullRequest, error)
+	UpdateTitle(ctx context.Context, prID string, title string) (*m.PullRequest, error)
+	GetHistory(ctx context.Context, prID string) ([]m.PREvent, error)
+	SetIsActive(ctx context.Context, request m.SetActiveRequest) (*m.DeactivationReport, error)
+	DeactivateTeam(ctx context.Context, request m.DeactivateTeamRequest) (*m.TeamDeactivationReport, error)
+	RemoveTeamMember(ctx context.Context, request m.RemoveTeamMemberRequest) (*m.TeamMemberRemovalReport, error)
+	MoveTeamMember(ctx context.Context, request m.MoveTeamRequest) (*m.User, error)
+	CreateTeam(ctx context.Context, team *m.Team) (*m.Team, error)
+	ReassignAbsentReviews(ctx context.Context, userID string) (int, []m.UnfilledReview, []m.UnfilledReview, error)
+	RunReviewQueue(ctx context.Context)
 }
 
 type prService struct {
 	prRepo      repo.PRRepository
 	userService UserService
 	teamService TeamService
+	strategies  ReviewerStrategies
+	publisher   EventPublisher
 	trManager   *manager.Manager
 }
 
-func NewPRService(prRepo repo.PRRepository, userService UserService, teamService TeamService, trManager *manager.Manager) PRService {
+func NewPRService(prRepo repo.PRRepository, userService UserService, teamService TeamService, strategies ReviewerStrategies, publisher EventPublisher, trManager *manager.Manager) PRService {
 	return &prService{
 		prRepo:      prRepo,
 		userService: userService,
 		teamService: teamService,
+		strategies:  strategies,
+		publisher:   publisher,
 		trManager:   trManager,
 	}
 }
@@ -41,6 +62,9 @@ func NewPRService(prRepo repo.PRRepository, userService UserService, teamService
 func (s *prService) GetPR(ctx context.Context, prID string) (*m.PullRequest, error) {
 	const method = "PRService.GetPR"
 
+	ctx, span := tracing.Start(ctx, method, attribute.String("pr_id", prID))
+	defer span.End()
+
 	pr, err := s.prRepo.GetByID(ctx, prID)
 	if err != nil {
 		slog.Error("failed to get PR",
@@ -48,6 +72,7 @@ func (s *prService) GetPR(ctx context.Context, prID string) (*m.PullRequest, err
 			"pr_id", prID,
 			"error", err,
 		)
+		tracing.RecordError(span, err)
 		return nil, errors.WrapInternal(err, "failed to get PR")
 	}
 
@@ -65,6 +90,12 @@ func (s *prService) GetPR(ctx context.Context, prID string) (*m.PullRequest, err
 func (s *prService) CreatePR(ctx context.Context, request m.CreatePRRequest) (*m.PullRequest, error) {
 	const method = "PRService.CreatePR"
 
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("pr_id", request.PullRequestID),
+		attribute.String("author_id", request.AuthorID),
+	)
+	defer span.End()
+
 	var createdPR *m.PullRequest
 	err := s.trManager.Do(ctx, func(ctx context.Context) error {
 		exists, err := s.prRepo.ExistsByID(ctx, request.PullRequestID)
@@ -89,19 +120,30 @@ func (s *prService) CreatePR(ctx context.Context, request m.CreatePRRequest) (*m
 			return err
 		}
 
-		team, err := s.teamService.GetTeam(ctx, author.TeamName)
-		if err != nil || team == nil {
+		teamName, err := prTeam(author, request.TeamName)
+		if err != nil {
 			return err
 		}
 
-		reviewers := findReviewersForPR(team, author.UserID)
-
 		pr := &m.PullRequest{
 			PullRequestID:     request.PullRequestID,
 			PullRequestName:   request.PullRequestName,
 			AuthorID:          request.AuthorID,
+			TeamName:          teamName,
 			Status:            m.StatusOpen,
-			AssignedReviewers: reviewers,
+			AssignedReviewers: []string{},
+		}
+
+		queued := 0
+		if request.Draft {
+			pr.Status = m.StatusDraft
+		} else {
+			pick, err := s.findReviewersForTeam(ctx, pr, request.ReviewersRequired)
+			if err != nil {
+				return err
+			}
+			pr.AssignedReviewers, pr.FallbackReviewers, queued = pick.reviewers, pick.fallbackReviewers, pick.queued
+			pr.MissingReviewers = pick.missing
 		}
 
 		if err := s.prRepo.Create(ctx, pr); err != nil {
@@ -114,21 +156,36 @@ func (s *prService) CreatePR(ctx context.Context, request m.CreatePRRequest) (*m
 			return errors.WrapInternal(err, "failed to create PR")
 		}
 
+		if err := s.recordAssignments(ctx, pr, m.ReasonAuto); err != nil {
+			return err
+		}
+
+		if err := s.queueReviews(ctx, pr.PullRequestID, queued, nil, m.ReasonAuto); err != nil {
+			return err
+		}
+
 		createdPR = pr
-		return nil
+		return s.publisher.Publish(ctx, m.NewEvent(m.EventPRCreated, pr))
 	})
 
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
+	metrics.PRsCreated.WithLabelValues(createdPR.TeamName).Inc()
+
 	return createdPR, nil
 }
 
-func (s *prService) MergePR(ctx context.Context, prID string) (*m.PullRequest, error) {
+func (s *prService) MergePR(ctx context.Context, prID string, force bool) (*m.PullRequest, error) {
 	const method = "PRService.MergePR"
 
+	ctx, span := tracing.Start(ctx, method, attribute.String("pr_id", prID))
+	defer span.End()
+
 	var mergedPR *m.PullRequest
+	merged := false
 	err := s.trManager.Do(ctx, func(ctx context.Context) error {
 		pr, err := s.GetPR(ctx, prID)
 		if err != nil || pr == nil {
@@ -144,8 +201,16 @@ func (s *prService) MergePR(ctx context.Context, prID string) (*m.PullRequest, e
 			return nil
 		}
 
+		if err := checkTransition(pr, m.StatusMerged); err != nil {
+			return err
+		}
+
+		if err := s.checkApprovals(ctx, pr, force); err != nil {
+			return err
+		}
+
 		mergedAt := time.Now()
-		if err := s.prRepo.UpdateStatus(ctx, prID, string(m.StatusMerged), &mergedAt); err != nil {
+		if err := s.prRepo.UpdateStatus(ctx, prID, string(m.StatusMerged), &mergedAt, nil); err != nil {
 			slog.Error("failed to update PR status",
 				"method", method,
 				"pr_id", prID,
@@ -156,137 +221,912 @@ func (s *prService) MergePR(ctx context.Context, prID string) (*m.PullRequest, e
 
 		pr.Status = m.StatusMerged
 		pr.MergedAt = &mergedAt
+		pr.MergeForced = force
 		mergedPR = pr
-		return nil
+		merged = true
+		return s.publisher.Publish(ctx, m.NewEvent(m.EventPRMerged, pr))
 	})
 
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
+	if merged {
+		metrics.PRsMerged.WithLabelValues(strconv.FormatBool(force)).Inc()
+	}
+
 	return mergedPR, nil
 }
 
-func findReviewersForPR(team *m.Team, authorID string) []string {
-	var candidates []string
-	for _, member := range team.Members {
-		if member.UserID != authorID && member.IsActive {
-			candidates = append(candidates, member.UserID)
+func (s *prService) ClosePR(ctx context.Context, prID string) (*m.PullRequest, error) {
+	const method = "PRService.ClosePR"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("pr_id", prID))
+	defer span.End()
+
+	var closedPR *m.PullRequest
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		pr, err := s.GetPR(ctx, prID)
+		if err != nil || pr == nil {
+			return err
+		}
+
+		if pr.Status == m.StatusClosed {
+			slog.Warn("PR already closed",
+				"method", method,
+				"pr_id", prID,
+			)
+			closedPR = pr
+			return nil
+		}
+
+		if err := checkTransition(pr, m.StatusClosed); err != nil {
+			return err
+		}
+
+		closedAt := time.Now()
+		if err := s.prRepo.UpdateStatus(ctx, prID, string(m.StatusClosed), nil, &closedAt); err != nil {
+			slog.Error("failed to update PR status",
+				"method", method,
+				"pr_id", prID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to update PR status")
+		}
+
+		if err := s.recordStatusChange(ctx, prID, m.PREventClosed); err != nil {
+			return err
 		}
+
+		pr.Status = m.StatusClosed
+		pr.ClosedAt = &closedAt
+		closedPR = pr
+		return s.publisher.Publish(ctx, m.NewEvent(m.EventPRClosed, pr))
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
 	}
 
-	if len(candidates) == 0 {
-		return []string{}
+	return closedPR, nil
+}
+
+func (s *prService) ReopenPR(ctx context.Context, prID string) (*m.PullRequest, error) {
+	const method = "PRService.ReopenPR"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("pr_id", prID))
+	defer span.End()
+
+	var reopenedPR *m.PullRequest
+	var replaced map[m.AssignmentReason]int
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		pr, err := s.GetPR(ctx, prID)
+		if err != nil || pr == nil {
+			return err
+		}
+
+		if pr.Status == m.StatusReopened {
+			slog.Warn("PR already reopened",
+				"method", method,
+				"pr_id", prID,
+			)
+			reopenedPR = pr
+			return nil
+		}
+
+		if err := checkTransition(pr, m.StatusReopened); err != nil {
+			return err
+		}
+
+		if err := s.prRepo.UpdateStatus(ctx, prID, string(m.StatusReopened), nil, nil); err != nil {
+			slog.Error("failed to update PR status",
+				"method", method,
+				"pr_id", prID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to update PR status")
+		}
+
+		if err := s.recordStatusChange(ctx, prID, m.PREventReopened); err != nil {
+			return err
+		}
+
+		pr.Status = m.StatusReopened
+		pr.ClosedAt = nil
+
+		// A PR closed while still a draft has nobody to review it after reopening
+		if len(pr.AssignedReviewers) == 0 {
+			if err := s.assignReviewers(ctx, pr, nil); err != nil {
+				return err
+			}
+		} else {
+			replaced, err = s.replaceUnavailableReviewers(ctx, pr)
+			if err != nil {
+				return err
+			}
+		}
+
+		reopenedPR = pr
+		return s.publisher.Publish(ctx, m.NewEvent(m.EventPRReopened, pr))
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
 	}
 
-	if len(candidates) <= 2 {
-		return candidates
+	for reason, count := range replaced {
+		metrics.Reassignments.WithLabelValues(string(reason)).Add(float64(count))
 	}
 
-	// Generate a permutation of indices
-	perm := rand.Perm(len(candidates))
-	selected := []string{
-		candidates[perm[0]],
-		candidates[perm[1]],
+	return reopenedPR, nil
+}
+
+// replaceUnavailableReviewers hands the reviews of reviewers who became
+// inactive or absent while the PR was closed over to other candidates. A
+// reviewer without a candidate to replace them keeps the review. It returns
+// the number of replacements by reason.
+func (s *prService) replaceUnavailableReviewers(ctx context.Context, pr *m.PullRequest) (map[m.AssignmentReason]int, error) {
+	const method = "PRService.replaceUnavailableReviewers"
+
+	reviewers, err := s.prRepo.GetUnavailableReviewers(ctx, pr.PullRequestID)
+	if err != nil {
+		slog.Error("failed to get unavailable reviewers",
+			"method", method,
+			"pr_id", pr.PullRequestID,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to get unavailable reviewers")
 	}
 
-	return selected
+	replaced := make(map[m.AssignmentReason]int)
+	for _, reviewer := range reviewers {
+		reason := m.ReasonDeactivation
+		if reviewer.IsActive {
+			reason = m.ReasonAbsence
+		}
+
+		replacement, _, err := s.replaceReviewer(ctx, pr, &m.User{UserID: reviewer.UserID}, reason)
+		if err == errors.ErrNoCandidate || err == errors.ErrNoCapacity {
+			slog.Warn("no replacement for unavailable reviewer of reopened PR",
+				"method", method,
+				"pr_id", pr.PullRequestID,
+				"reviewer_id", reviewer.UserID,
+			)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if replacement != nil {
+			replaced[reason]++
+		}
+	}
+
+	return replaced, nil
 }
 
-func (s *prService) ReassignReviewer(ctx context.Context, prID string, oldUserID string) (resultPR *m.PullRequest, newReviewerID *string, retErr error) {
-	const method = "PRService.ReassignReviewer"
+func (s *prService) MarkReady(ctx context.Context, request m.MarkReadyRequest) (*m.PullRequest, error) {
+	const method = "PRService.MarkReady"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("pr_id", request.PullRequestID))
+	defer span.End()
 
+	var readyPR *m.PullRequest
 	err := s.trManager.Do(ctx, func(ctx context.Context) error {
-		pr, err := s.GetPR(ctx, prID)
+		pr, err := s.GetPR(ctx, request.PullRequestID)
 		if err != nil || pr == nil {
 			return err
 		}
 
-		if pr.Status == m.StatusMerged {
-			slog.Error("cannot reassign on merged PR",
+		if pr.Status.IsOpen() {
+			slog.Warn("PR already ready for review",
 				"method", method,
-				"pr_id", prID,
+				"pr_id", request.PullRequestID,
 			)
-			return errors.ErrPRMerged
+			readyPR = pr
+			return nil
 		}
 
-		oldReviewer, err := s.userService.GetUser(ctx, oldUserID)
-		if err != nil || oldReviewer == nil {
+		if err := checkTransition(pr, m.StatusOpen); err != nil {
 			return err
 		}
 
-		team, err := s.teamService.GetTeam(ctx, oldReviewer.TeamName)
-		if err != nil || team == nil {
+		if err := s.prRepo.UpdateStatus(ctx, request.PullRequestID, string(m.StatusOpen), nil, nil); err != nil {
+			slog.Error("failed to update PR status",
+				"method", method,
+				"pr_id", request.PullRequestID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to update PR status")
+		}
+		pr.Status = m.StatusOpen
+
+		if err := s.assignReviewers(ctx, pr, request.ReviewersRequired); err != nil {
 			return err
 		}
 
-		if !slices.Contains(pr.AssignedReviewers, oldUserID) {
-			slog.Error("reviewer is not assigned to this PR",
+		readyPR = pr
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return readyPR, nil
+}
+
+func (s *prService) UpdateTitle(ctx context.Context, prID string, title string) (*m.PullRequest, error) {
+	const method = "PRService.UpdateTitle"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("pr_id", prID))
+	defer span.End()
+
+	var updatedPR *m.PullRequest
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		pr, err := s.GetPR(ctx, prID)
+		if err != nil || pr == nil {
+			return err
+		}
+
+		if pr.PullRequestName == title {
+			updatedPR = pr
+			return nil
+		}
+
+		if err := s.prRepo.UpdateTitle(ctx, prID, title); err != nil {
+			slog.Error("failed to update PR title",
 				"method", method,
 				"pr_id", prID,
-				"old_user_id", oldUserID,
+				"error", err,
 			)
-			return errors.ErrNotAssigned
+			return errors.WrapInternal(err, "failed to update PR title")
 		}
 
-		replacement := s.findReplacementReviewer(team, pr.AuthorID, pr.AssignedReviewers, oldUserID)
-		if replacement == nil {
-			slog.Error("no active replacement candidate in team",
+		pr.PullRequestName = title
+		updatedPR = pr
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return updatedPR, nil
+}
+
+var prTransitions = map[m.PRStatus][]m.PRStatus{
+	m.StatusDraft:    {m.StatusOpen, m.StatusClosed},
+	m.StatusOpen:     {m.StatusMerged, m.StatusClosed},
+	m.StatusReopened: {m.StatusMerged, m.StatusClosed},
+	m.StatusClosed:   {m.StatusReopened},
+}
+
+func checkTransition(pr *m.PullRequest, to m.PRStatus) error {
+	const method = "PRService.checkTransition"
+
+	if slices.Contains(prTransitions[pr.Status], to) {
+		return nil
+	}
+
+	slog.Error("invalid PR status transition",
+		"method", method,
+		"pr_id", pr.PullRequestID,
+		"from", pr.Status,
+		"to", to,
+	)
+	return errors.NewInvalidStatusTransition(fmt.Sprintf("cannot change PR status from %s to %s", pr.Status, to))
+}
+
+func (s *prService) assignReviewers(ctx context.Context, pr *m.PullRequest, reviewersRequired *int) error {
+	const method = "PRService.assignReviewers"
+
+	pick, err := s.findReviewersForTeam(ctx, pr, reviewersRequired)
+	if err != nil {
+		return err
+	}
+
+	if err := s.prRepo.AddReviewers(ctx, pr.PullRequestID, pick.reviewers, pick.fallbackReviewers); err != nil {
+		slog.Error("failed to assign reviewers",
+			"method", method,
+			"pr_id", pr.PullRequestID,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to assign reviewers")
+	}
+
+	pr.AssignedReviewers = pick.reviewers
+	pr.FallbackReviewers = pick.fallbackReviewers
+	pr.MissingReviewers = pick.missing
+	if err := s.recordAssignments(ctx, pr, m.ReasonAuto); err != nil {
+		return err
+	}
+
+	return s.queueReviews(ctx, pr.PullRequestID, pick.queued, nil, m.ReasonAuto)
+}
+
+// recordAssignments appends an ASSIGNED history entry for every reviewer
+// currently assigned to the PR.
+func (s *prService) recordAssignments(ctx context.Context, pr *m.PullRequest, reason m.AssignmentReason) error {
+	const method = "PRService.recordAssignments"
+
+	actorID := u.ActorFromContext(ctx)
+	events := make([]m.PREvent, len(pr.AssignedReviewers))
+	for i, reviewerID := range pr.AssignedReviewers {
+		events[i] = m.PREvent{
+			PullRequestID: pr.PullRequestID,
+			EventType:     m.PREventAssigned,
+			ReviewerID:    reviewerID,
+			ActorID:       actorID,
+			Reason:        reason,
+		}
+		if team, ok := pr.FallbackReviewers[reviewerID]; ok {
+			events[i].FallbackTeam = &team
+		}
+	}
+
+	if err := s.prRepo.CreateEvents(ctx, events); err != nil {
+		slog.Error("failed to record reviewer assignments",
+			"method", method,
+			"pr_id", pr.PullRequestID,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to record reviewer assignments")
+	}
+
+	return nil
+}
+
+func (s *prService) checkApprovals(ctx context.Context, pr *m.PullRequest, force bool) error {
+	const method = "PRService.checkApprovals"
+
+	approvalsRequired := defaultApprovalsRequired
+	if pr.TeamName != "" {
+		team, err := s.teamService.GetTeam(ctx, pr.TeamName)
+		if err != nil {
+			return err
+		}
+		approvalsRequired = *team.ApprovalsRequired
+	}
+
+	approvals := 0
+	for _, review := range pr.Reviews {
+		if review.State == m.ReviewApproved && slices.Contains(pr.AssignedReviewers, review.ReviewerID) {
+			approvals++
+		}
+	}
+
+	if approvals >= approvalsRequired {
+		return nil
+	}
+
+	if !force {
+		slog.Error("PR does not have enough approvals",
+			"method", method,
+			"pr_id", pr.PullRequestID,
+			"approvals", approvals,
+			"approvals_required", approvalsRequired,
+		)
+		return errors.ErrNotApproved
+	}
+
+	slog.Warn("PR merge forced without required approvals",
+		"method", method,
+		"pr_id", pr.PullRequestID,
+		"approvals", approvals,
+		"approvals_required", approvalsRequired,
+	)
+
+	if err := s.prRepo.SetMergeForced(ctx, pr.PullRequestID); err != nil {
+		slog.Error("failed to record forced merge",
+			"method", method,
+			"pr_id", pr.PullRequestID,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to record forced merge")
+	}
+
+	return s.recordStatusChange(ctx, pr.PullRequestID, m.PREventMergeForced)
+}
+
+// recordStatusChange adds a change of the PR status made by the actor to the
+// PR history.
+func (s *prService) recordStatusChange(ctx context.Context, prID string, eventType m.PREventType) error {
+	const method = "PRService.recordStatusChange"
+
+	event := m.PREvent{
+		PullRequestID: prID,
+		EventType:     eventType,
+		ActorID:       u.ActorFromContext(ctx),
+		Reason:        m.ReasonManual,
+	}
+	if err := s.prRepo.CreateEvents(ctx, []m.PREvent{event}); err != nil {
+		slog.Error("failed to record PR status change",
+			"method", method,
+			"pr_id", prID,
+			"event_type", eventType,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to record PR status change")
+	}
+
+	return nil
+}
+
+// prTeam returns the team a new PR of the author belongs to: the requested
+// one, which the author has to be a member of, or the author's primary team.
+func prTeam(author *m.User, requested string) (string, error) {
+	if requested == "" {
+		if len(author.Teams) == 0 {
+			return "", errors.ErrUserHasNoTeam
+		}
+		return author.PrimaryTeam(), nil
+	}
+
+	if !author.IsMemberOf(requested) {
+		return "", errors.ErrNotTeamMember
+	}
+
+	return requested, nil
+}
+
+// reviewTeam returns the team whose members and fallback teams review the PR.
+// PRs without a team, e.g. those whose team was deleted, are reviewed by the
+// author's primary team.
+func (s *prService) reviewTeam(ctx context.Context, pr *m.PullRequest) (*m.Team, error) {
+	teamName := pr.TeamName
+	if teamName == "" {
+		author, err := s.userService.GetUser(ctx, pr.AuthorID)
+		if err != nil {
+			return nil, err
+		}
+		if len(author.Teams) == 0 {
+			return nil, errors.ErrUserHasNoTeam
+		}
+		teamName = author.PrimaryTeam()
+	}
+
+	return s.teamService.GetTeam(ctx, teamName)
+}
+
+func (s *prService) findReviewersForTeam(ctx context.Context, pr *m.PullRequest, reviewersRequired *int) (*reviewerPick, error) {
+	team, err := s.reviewTeam(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	count := team.ReviewersRequired
+	if reviewersRequired != nil {
+		count = *reviewersRequired
+	}
+
+	return s.findReviewersForPR(ctx, team, pr.AuthorID, count, reviewersRequired != nil)
+}
+
+// findReviewersForPR picks count reviewers for a new assignment. Slots that
+// only candidates at capacity could take are handled by the team's capacity
+// policy; queued slots count as filled. An explicitly requested count that
+// cannot be met fails the assignment, while a shortfall against the team
+// default is reported in the result, so that a temporarily short-handed team
+// can still open PRs.
+func (s *prService) findReviewersForPR(ctx context.Context, team *m.Team, authorID string, count int, strict bool) (*reviewerPick, error) {
+	const method = "PRService.findReviewersForPR"
+
+	pick, err := s.pickReviewers(ctx, team, count, []string{authorID})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyCapacityPolicy(team, pick, count); err != nil {
+		return nil, err
+	}
+
+	if strict && len(pick.reviewers)+pick.queued < count {
+		slog.Error("not enough candidates for requested number of reviewers",
+			"method", method,
+			"team_name", team.TeamName,
+			"requested", count,
+			"candidates", len(pick.reviewers),
+		)
+		return nil, errors.ErrNotEnough
+	}
+
+	if missing := count - len(pick.reviewers) - pick.queued; missing > 0 {
+		slog.Warn("not enough eligible candidates for team reviewers_required",
+			"method", method,
+			"team_name", team.TeamName,
+			"required", count,
+			"missing", missing,
+		)
+		metrics.UnderAssignedPRs.WithLabelValues(team.TeamName).Inc()
+		pick.missing = missing
+	}
+
+	return pick, nil
+}
+
+func (s *prService) ReassignReviewer(ctx context.Context, prID string, oldUserID string) (resultPR *m.PullRequest, newReviewerID *string, retErr error) {
+	const method = "PRService.ReassignReviewer"
+
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("pr_id", prID),
+		attribute.String("old_reviewer_id", oldUserID),
+	)
+	defer span.End()
+
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		pr, err := s.GetPR(ctx, prID)
+		if err != nil || pr == nil {
+			return err
+		}
+
+		if pr.Status == m.StatusMerged {
+			slog.Error("cannot reassign on merged PR",
 				"method", method,
-				"team_name", oldReviewer.TeamName,
-				"old_user_id", oldUserID,
+				"pr_id", prID,
 			)
-			return errors.ErrNoCandidate
+			return errors.ErrPRMerged
 		}
 
-		if err := s.prRepo.UpdateReviewer(ctx, prID, oldUserID, *replacement); err != nil {
-			slog.Error("failed to update reviewer",
+		if !pr.Status.IsOpen() {
+			slog.Error("cannot reassign on PR that is not open",
 				"method", method,
 				"pr_id", prID,
-				"old_user_id", oldUserID,
-				"new_user_id", replacement,
-				"error", err,
+				"status", pr.Status,
 			)
-			return errors.WrapInternal(err, "failed to update reviewer")
+			return errors.ErrPRNotOpen
+		}
+
+		oldReviewer, err := s.userService.GetUser(ctx, oldUserID)
+		if err != nil || oldReviewer == nil {
+			return err
+		}
+
+		replacement, _, err := s.replaceReviewer(ctx, pr, oldReviewer, m.ReasonManual)
+		if err != nil {
+			return err
 		}
 
-		pr.AssignedReviewers = u.ReplaceInSlice(pr.AssignedReviewers, oldUserID, *replacement)
 		resultPR = pr
 		newReviewerID = replacement
 		return nil
 	})
 
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, nil, err
 	}
 
+	if newReviewerID != nil {
+		metrics.Reassignments.WithLabelValues(string(m.ReasonManual)).Inc()
+	}
+
 	return resultPR, newReviewerID, nil
 }
 
-func (s *prService) findReplacementReviewer(team *m.Team, authorID string, currentReviewers []string, oldUserID string) *string {
-	var candidates []string
+// SetIsActive changes the user's activity and, when the user is deactivated
+// with ReassignReviews set, hands their open reviews over to other candidates
+// in the same transaction.
+func (s *prService) SetIsActive(ctx context.Context, request m.SetActiveRequest) (*m.DeactivationReport, error) {
+	const method = "PRService.SetIsActive"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", request.UserID))
+	defer span.End()
+
+	var report *m.DeactivationReport
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		user, err := s.userService.SetIsActive(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		report = &m.DeactivationReport{
+			User:        user,
+			Reassigned:  []m.ReviewerReassignment{},
+			NoCandidate: []string{},
+			Queued:      []string{},
+		}
+		if user.IsActive || !request.ReassignReviews {
+			return nil
+		}
+
+		prs, err := s.prRepo.GetByReviewer(ctx, user.UserID)
+		if err != nil {
+			slog.Error("failed to get PRs by reviewer",
+				"method", method,
+				"user_id", user.UserID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to get PRs by reviewer")
+		}
+
+		for _, short := range prs {
+			if !short.Status.IsOpen() {
+				continue
+			}
+
+			pr, err := s.GetPR(ctx, short.PullRequestID)
+			if err != nil {
+				return err
+			}
 
+			replacement, queued, err := s.replaceReviewer(ctx, pr, user, m.ReasonDeactivation)
+			if err == errors.ErrNoCandidate || err == errors.ErrNoCapacity {
+				report.NoCandidate = append(report.NoCandidate, pr.PullRequestID)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if queued {
+				report.Queued = append(report.Queued, pr.PullRequestID)
+				continue
+			}
+
+			report.Reassigned = append(report.Reassigned, m.ReviewerReassignment{
+				PullRequestID: pr.PullRequestID,
+				NewReviewerID: *replacement,
+			})
+		}
+
+		slog.Info("reviews of deactivated user reassigned",
+			"method", method,
+			"user_id", user.UserID,
+			"reassigned", len(report.Reassigned),
+			"no_candidate", len(report.NoCandidate),
+			"queued", len(report.Queued),
+		)
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	metrics.Reassignments.WithLabelValues(string(m.ReasonDeactivation)).Add(float64(len(report.Reassigned)))
+
+	return report, nil
+}
+
+// replaceReviewer replaces oldReviewer on the PR with a candidate from the
+// PR's review team or its fallback teams, records the change and publishes the event.
+// When the team's capacity policy leaves the slot unfilled, the replacement is
+// queued, oldReviewer keeps the review for now and queued is reported instead
+// of a replacement.
+func (s *prService) replaceReviewer(ctx context.Context, pr *m.PullRequest, oldReviewer *m.User, reason m.AssignmentReason) (*string, bool, error) {
+	const method = "PRService.replaceReviewer"
+
+	prID := pr.PullRequestID
+	oldUserID := oldReviewer.UserID
+
+	if !slices.Contains(pr.AssignedReviewers, oldUserID) {
+		slog.Error("reviewer is not assigned to this PR",
+			"method", method,
+			"pr_id", prID,
+			"old_user_id", oldUserID,
+		)
+		return nil, false, errors.ErrNotAssigned
+	}
+
+	team, err := s.reviewTeam(ctx, pr)
+	if err != nil {
+		return nil, false, err
+	}
+
+	pick, err := s.findReplacementReviewer(ctx, team, pr.AuthorID, pr.AssignedReviewers, oldUserID)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(pick.reviewers) == 0 && pick.queued > 0 {
+		slog.Info("reviewer replacement queued",
+			"method", method,
+			"pr_id", prID,
+			"old_user_id", oldUserID,
+		)
+		return nil, true, s.queueReviews(ctx, prID, 1, &oldUserID, reason)
+	}
+	if len(pick.reviewers) == 0 {
+		slog.Error("no active replacement candidate in team or its fallback teams",
+			"method", method,
+			"team_name", team.TeamName,
+			"old_user_id", oldUserID,
+		)
+		metrics.NoCandidateFailures.WithLabelValues(team.TeamName).Inc()
+		return nil, false, errors.ErrNoCandidate
+	}
+
+	replacement := pick.reviewers[0]
+	var fallbackTeam *string
+	if fallbackName, ok := pick.fallbackReviewers[replacement]; ok {
+		fallbackTeam = &fallbackName
+	}
+
+	if err := s.swapReviewer(ctx, pr, oldUserID, replacement, fallbackTeam, reason); err != nil {
+		return nil, false, err
+	}
+
+	return &replacement, false, nil
+}
+
+// swapReviewer hands the review of oldUserID on the PR over to replacement,
+// records the change and publishes the event.
+func (s *prService) swapReviewer(ctx context.Context, pr *m.PullRequest, oldUserID string, replacement string, fallbackTeam *string, reason m.AssignmentReason) error {
+	const method = "PRService.swapReviewer"
+
+	prID := pr.PullRequestID
+
+	if err := s.prRepo.UpdateReviewer(ctx, prID, oldUserID, replacement, fallbackTeam); err != nil {
+		slog.Error("failed to update reviewer",
+			"method", method,
+			"pr_id", prID,
+			"old_user_id", oldUserID,
+			"new_user_id", replacement,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to update reviewer")
+	}
+
+	event := m.PREvent{
+		PullRequestID:      prID,
+		EventType:          m.PREventReplaced,
+		ReviewerID:         replacement,
+		PreviousReviewerID: &oldUserID,
+		FallbackTeam:       fallbackTeam,
+		ActorID:            u.ActorFromContext(ctx),
+		Reason:             reason,
+	}
+	if err := s.prRepo.CreateEvents(ctx, []m.PREvent{event}); err != nil {
+		slog.Error("failed to record reviewer replacement",
+			"method", method,
+			"pr_id", prID,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to record reviewer replacement")
+	}
+
+	pr.AssignedReviewers = u.ReplaceInSlice(pr.AssignedReviewers, oldUserID, replacement)
+	delete(pr.FallbackReviewers, oldUserID)
+	if fallbackTeam != nil {
+		if pr.FallbackReviewers == nil {
+			pr.FallbackReviewers = make(map[string]string)
+		}
+		pr.FallbackReviewers[replacement] = *fallbackTeam
+	}
+
+	return s.publisher.Publish(ctx, m.NewEvent(m.EventReviewerReassigned, m.ReviewerReassignedData{
+		PullRequest:   pr,
+		OldReviewerID: oldUserID,
+		NewReviewerID: replacement,
+	}))
+}
+
+// findReplacementReviewer picks one reviewer to take over the review of
+// oldUserID, applying the team's capacity policy when every candidate is at
+// capacity.
+func (s *prService) findReplacementReviewer(ctx context.Context, team *m.Team, authorID string, currentReviewers []string, oldUserID string) (*reviewerPick, error) {
+	excluded := append([]string{authorID, oldUserID}, currentReviewers...)
+
+	pick, err := s.pickReviewers(ctx, team, 1, excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyCapacityPolicy(team, pick, 1); err != nil {
+		return nil, err
+	}
+
+	return pick, nil
+}
+
+// pickReviewers selects up to count available reviewers with spare capacity
+// from the team, falling back to the team's fallback teams in priority order
+// while slots remain. Candidates skipped for capacity are collected in the
+// result, so that the caller can apply the capacity policy.
+func (s *prService) pickReviewers(ctx context.Context, team *m.Team, count int, excluded []string) (*reviewerPick, error) {
+	selected, saturated, err := s.pickFromTeam(ctx, team, count, excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	pick := &reviewerPick{reviewers: selected, saturated: saturated}
+	for _, fallbackName := range team.FallbackTeams {
+		if len(pick.reviewers) >= count {
+			break
+		}
+
+		fallbackTeam, err := s.teamService.GetTeam(ctx, fallbackName)
+		if err != nil {
+			return nil, err
+		}
+
+		selected, saturated, err := s.pickFromTeam(ctx, fallbackTeam, count-len(pick.reviewers),
+			slices.Concat(excluded, pick.reviewers, pick.saturatedIDs()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, reviewerID := range selected {
+			pick.add(reviewerID, fallbackName)
+		}
+		for _, reviewer := range saturated {
+			reviewer.fallbackTeam = fallbackName
+			pick.saturated = append(pick.saturated, reviewer)
+		}
+	}
+
+	return pick, nil
+}
+
+func (s *prService) pickFromTeam(ctx context.Context, team *m.Team, count int, excluded []string) ([]string, []saturatedReviewer, error) {
+	var candidates []string
 	for _, member := range team.Members {
-		if member.UserID != authorID &&
-			member.IsActive &&
-			member.UserID != oldUserID &&
-			!slices.Contains(currentReviewers, member.UserID) {
+		if member.IsAvailable() && !slices.Contains(excluded, member.UserID) {
 			candidates = append(candidates, member.UserID)
 		}
 	}
 
+	candidates, saturated, err := s.splitByCapacity(ctx, candidates, reviewLimits(team))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if len(candidates) == 0 {
-		return nil
+		return []string{}, saturated, nil
+	}
+
+	selected, err := s.selectReviewers(ctx, team, candidates, count)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Return random candidate
-	randomIndex := rand.Intn(len(candidates))
-	return &candidates[randomIndex]
+	return selected, saturated, nil
+}
+
+func (s *prService) selectReviewers(ctx context.Context, team *m.Team, candidates []string, count int) ([]string, error) {
+	return s.strategies.For(team).Select(ctx, SelectionRequest{
+		Team:       team,
+		Candidates: candidates,
+		Count:      count,
+	})
+}
+
+func (s *prService) GetHistory(ctx context.Context, prID string) ([]m.PREvent, error) {
+	const method = "PRService.GetHistory"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("pr_id", prID))
+	defer span.End()
+
+	if _, err := s.GetPR(ctx, prID); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	events, err := s.prRepo.GetEvents(ctx, prID)
+	if err != nil {
+		slog.Error("failed to get PR history",
+			"method", method,
+			"pr_id", prID,
+			"error", err,
+		)
+		tracing.RecordError(span, err)
+		return nil, errors.WrapInternal(err, "failed to get PR history")
+	}
+
+	return events, nil
 }
 
 func (s *prService) GetPRByReviewer(ctx context.Context, userID string) ([]m.PullRequestShort, error) {
 	const method = "PRService.GetPRByReviewer"
 
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
 	prs, err := s.prRepo.GetByReviewer(ctx, userID)
 	if err != nil {
 		slog.Error("failed to get PRs by reviewer",
@@ -294,8 +1134,81 @@ func (s *prService) GetPRByReviewer(ctx context.Context, userID string) ([]m.Pul
 			"user_id", userID,
 			"error", err,
 		)
+		tracing.RecordError(span, err)
 		return nil, errors.WrapInternal(err, "failed to get PRs by reviewer")
 	}
 
 	return prs, nil
 }
+
+func (s *prService) SubmitReview(ctx context.Context, request m.SubmitReviewRequest) (*m.PullRequest, error) {
+	const method = "PRService.SubmitReview"
+
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("pr_id", request.PullRequestID),
+		attribute.String("reviewer_id", request.ReviewerID),
+	)
+	defer span.End()
+
+	var reviewedPR *m.PullRequest
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		pr, err := s.GetPR(ctx, request.PullRequestID)
+		if err != nil || pr == nil {
+			return err
+		}
+
+		if pr.Status == m.StatusMerged {
+			slog.Error("cannot review merged PR",
+				"method", method,
+				"pr_id", request.PullRequestID,
+			)
+			return errors.ErrReviewMerged
+		}
+
+		if !pr.Status.IsOpen() {
+			slog.Error("cannot review PR that is not open",
+				"method", method,
+				"pr_id", request.PullRequestID,
+				"status", pr.Status,
+			)
+			return errors.ErrPRNotOpen
+		}
+
+		if !slices.Contains(pr.AssignedReviewers, request.ReviewerID) {
+			slog.Error("reviewer is not assigned to this PR",
+				"method", method,
+				"pr_id", request.PullRequestID,
+				"reviewer_id", request.ReviewerID,
+			)
+			return errors.ErrNotAssigned
+		}
+
+		review := &m.Review{
+			PullRequestID: request.PullRequestID,
+			ReviewerID:    request.ReviewerID,
+			State:         request.State,
+			Comment:       request.Comment,
+			SubmittedAt:   time.Now(),
+		}
+
+		if err := s.prRepo.CreateReview(ctx, review); err != nil {
+			slog.Error("failed to create review",
+				"method", method,
+				"pr_id", request.PullRequestID,
+				"reviewer_id", request.ReviewerID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to create review")
+		}
+
+		reviewedPR, err = s.GetPR(ctx, request.PullRequestID)
+		return err
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return reviewedPR, nil
+}
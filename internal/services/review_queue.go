@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+)
+
+const (
+	reviewQueueBatchSize    = 20
+	reviewQueuePollInterval = time.Minute
+)
+
+// RunReviewQueue fills the review slots left unfilled by the LEAVE_UNFILLED
+// capacity policy as reviewers get below their limits, until ctx is canceled.
+func (s *prService) RunReviewQueue(ctx context.Context) {
+	ticker := time.NewTicker(reviewQueuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.fillQueue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *prService) fillQueue(ctx context.Context) {
+	const method = "PRService.fillQueue"
+
+	var afterID int64
+	for ctx.Err() == nil {
+		lastID, processed, err := s.fillQueueBatch(ctx, afterID)
+		if err != nil {
+			slog.Error("failed to fill queued reviews",
+				"method", method,
+				"error", err,
+			)
+			return
+		}
+
+		if processed < reviewQueueBatchSize {
+			return
+		}
+		afterID = lastID
+	}
+}
+
+// fillQueueBatch tries to fill the queued slots following afterID and removes
+// those that were filled or are no longer needed. Slots still without a
+// candidate stay in the queue. Each slot is handled in its own transaction, so
+// a slot that fails is logged and skipped without undoing the others.
+func (s *prService) fillQueueBatch(ctx context.Context, afterID int64) (int64, int, error) {
+	const method = "PRService.fillQueueBatch"
+
+	lastID := afterID
+	for processed := 0; processed < reviewQueueBatchSize; processed++ {
+		var review *m.QueuedReview
+		err := s.trManager.Do(ctx, func(ctx context.Context) error {
+			reviews, err := s.prRepo.GetQueuedReviewsForUpdate(ctx, lastID, 1)
+			if err != nil {
+				slog.Error("failed to get queued reviews",
+					"method", method,
+					"error", err,
+				)
+				return errors.WrapInternal(err, "failed to get queued reviews")
+			}
+			if len(reviews) == 0 {
+				return nil
+			}
+
+			review = &reviews[0]
+			finished, err := s.fillQueuedReview(ctx, review)
+			if err != nil || !finished {
+				return err
+			}
+
+			if err := s.prRepo.DeleteQueuedReviews(ctx, []int64{review.ID}); err != nil {
+				slog.Error("failed to delete queued review",
+					"method", method,
+					"queued_review_id", review.ID,
+					"error", err,
+				)
+				return errors.WrapInternal(err, "failed to delete queued review")
+			}
+			return nil
+		})
+
+		if review == nil {
+			return lastID, processed, err
+		}
+		if err != nil {
+			slog.Error("failed to fill queued review",
+				"method", method,
+				"queued_review_id", review.ID,
+				"pr_id", review.PullRequestID,
+				"error", err,
+			)
+		}
+		lastID = review.ID
+	}
+
+	return lastID, reviewQueueBatchSize, nil
+}
+
+// fillQueuedReview assigns a reviewer with spare capacity to the queued slot.
+// It reports whether the slot can be removed from the queue: it was filled,
+// or the PR is no longer open, the reviewer to replace has left it or the
+// author of a PR without a team has no team to review it either.
+func (s *prService) fillQueuedReview(ctx context.Context, review *m.QueuedReview) (bool, error) {
+	const method = "PRService.fillQueuedReview"
+
+	pr, err := s.prRepo.GetByID(ctx, review.PullRequestID)
+	if err != nil {
+		slog.Error("failed to get PR",
+			"method", method,
+			"pr_id", review.PullRequestID,
+			"error", err,
+		)
+		return false, errors.WrapInternal(err, "failed to get PR")
+	}
+
+	if pr == nil || !pr.Status.IsOpen() {
+		return true, nil
+	}
+	replaces := review.ReplacesReviewerID
+	if replaces != nil && !slices.Contains(pr.AssignedReviewers, *replaces) {
+		return true, nil
+	}
+
+	team, err := s.reviewTeam(ctx, pr)
+	if err == errors.ErrUserHasNoTeam {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	excluded := append([]string{pr.AuthorID}, pr.AssignedReviewers...)
+	pick, err := s.pickReviewers(ctx, team, 1, excluded)
+	if err != nil {
+		return false, err
+	}
+	if len(pick.reviewers) == 0 {
+		return false, nil
+	}
+
+	reviewerID := pick.reviewers[0]
+	var fallbackTeam *string
+	if fallbackName, ok := pick.fallbackReviewers[reviewerID]; ok {
+		fallbackTeam = &fallbackName
+	}
+
+	if review.ActorID != nil {
+		ctx = u.WithActor(ctx, *review.ActorID)
+	}
+
+	if replaces != nil {
+		err = s.swapReviewer(ctx, pr, *replaces, reviewerID, fallbackTeam, review.Reason)
+	} else {
+		err = s.addReviewer(ctx, pr, reviewerID, fallbackTeam, review.Reason)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	slog.Info("queued review filled",
+		"method", method,
+		"pr_id", pr.PullRequestID,
+		"reviewer_id", reviewerID,
+		"replacement", replaces != nil,
+	)
+
+	return true, nil
+}
+
+// addReviewer assigns one more reviewer to an open PR, records the assignment
+// and publishes the event.
+func (s *prService) addReviewer(ctx context.Context, pr *m.PullRequest, reviewerID string, fallbackTeam *string, reason m.AssignmentReason) error {
+	const method = "PRService.addReviewer"
+
+	var fallbackReviewers map[string]string
+	if fallbackTeam != nil {
+		fallbackReviewers = map[string]string{reviewerID: *fallbackTeam}
+	}
+
+	if err := s.prRepo.AddReviewers(ctx, pr.PullRequestID, []string{reviewerID}, fallbackReviewers); err != nil {
+		slog.Error("failed to assign reviewer",
+			"method", method,
+			"pr_id", pr.PullRequestID,
+			"reviewer_id", reviewerID,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to assign reviewer")
+	}
+
+	event := m.PREvent{
+		PullRequestID: pr.PullRequestID,
+		EventType:     m.PREventAssigned,
+		ReviewerID:    reviewerID,
+		FallbackTeam:  fallbackTeam,
+		ActorID:       u.ActorFromContext(ctx),
+		Reason:        reason,
+	}
+	if err := s.prRepo.CreateEvents(ctx, []m.PREvent{event}); err != nil {
+		slog.Error("failed to record reviewer assignment",
+			"method", method,
+			"pr_id", pr.PullRequestID,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to record reviewer assignment")
+	}
+
+	pr.AssignedReviewers = append(pr.AssignedReviewers, reviewerID)
+	if fallbackTeam != nil {
+		if pr.FallbackReviewers == nil {
+			pr.FallbackReviewers = make(map[string]string)
+		}
+		pr.FallbackReviewers[reviewerID] = *fallbackTeam
+	}
+
+	return s.publisher.Publish(ctx, m.NewEvent(m.EventReviewerAssigned, m.ReviewerAssignedData{
+		PullRequest: pr,
+		ReviewerID:  reviewerID,
+	}))
+}
+
+// queueReviews queues count review slots of the PR. replaces is the reviewer
+// whose review the slots take over, or nil for additional reviewers.
+func (s *prService) queueReviews(ctx context.Context, prID string, count int, replaces *string, reason m.AssignmentReason) error {
+	const method = "PRService.queueReviews"
+
+	if count == 0 {
+		return nil
+	}
+
+	actorID := u.ActorFromContext(ctx)
+	reviews := make([]m.QueuedReview, count)
+	for i := range reviews {
+		reviews[i] = m.QueuedReview{
+			PullRequestID:      prID,
+			ReplacesReviewerID: replaces,
+			Reason:             reason,
+			ActorID:            actorID,
+		}
+	}
+
+	if err := s.prRepo.QueueReviews(ctx, reviews); err != nil {
+		slog.Error("failed to queue reviews",
+			"method", method,
+			"pr_id", prID,
+			"count", count,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to queue reviews")
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+// fakeQueueRepo is a review queue whose deletions apply only when the
+// transaction that made them commits. PRs listed in failing cannot be read.
+type fakeQueueRepo struct {
+	repo.PRRepository
+	queue   []m.QueuedReview
+	prs     map[string]*m.PullRequest
+	failing []string
+}
+
+func (r *fakeQueueRepo) GetQueuedReviewsForUpdate(_ context.Context, afterID int64, limit int) ([]m.QueuedReview, error) {
+	var reviews []m.QueuedReview
+	for _, review := range r.queue {
+		if review.ID > afterID && len(reviews) < limit {
+			reviews = append(reviews, review)
+		}
+	}
+	return reviews, nil
+}
+
+func (r *fakeQueueRepo) DeleteQueuedReviews(ctx context.Context, ids []int64) error {
+	stage(ctx, func() {
+		r.queue = slices.DeleteFunc(r.queue, func(review m.QueuedReview) bool {
+			return slices.Contains(ids, review.ID)
+		})
+	})
+	return nil
+}
+
+func (r *fakeQueueRepo) GetByID(_ context.Context, prID string) (*m.PullRequest, error) {
+	if slices.Contains(r.failing, prID) {
+		return nil, errors.New("connection reset")
+	}
+	return r.prs[prID], nil
+}
+
+func TestFillQueueSkipsFailingSlot(t *testing.T) {
+	queueRepo := &fakeQueueRepo{
+		prs: map[string]*m.PullRequest{
+			"pr-1": {PullRequestID: "pr-1", Status: m.StatusMerged},
+			"pr-3": {PullRequestID: "pr-3", Status: m.StatusClosed},
+		},
+		failing: []string{"pr-2"},
+	}
+	for i, prID := range []string{"pr-1", "pr-2", "pr-3"} {
+		queueRepo.queue = append(queueRepo.queue, m.QueuedReview{ID: int64(i + 1), PullRequestID: prID})
+	}
+	service := &prService{prRepo: queueRepo, trManager: newFakeTrManager()}
+
+	lastID, processed, err := service.fillQueueBatch(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("fillQueueBatch() error = %v", err)
+	}
+	if lastID != 3 || processed != 3 {
+		t.Errorf("fillQueueBatch() = %d, %d, want 3, 3", lastID, processed)
+	}
+
+	if len(queueRepo.queue) != 1 || queueRepo.queue[0].PullRequestID != "pr-2" {
+		t.Errorf("queue = %v, want only the failing slot of pr-2 left", queueRepo.queue)
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/jonx8/pr-review-service/internal/errors"
+	"github.com/jonx8/pr-review-service/internal/metrics"
+	m "github.com/jonx8/pr-review-service/internal/models"
+)
+
+// saturatedReviewer is a candidate skipped because they have reached their
+// limit of open reviews. fallbackTeam is empty for members of the PR's team.
+type saturatedReviewer struct {
+	userID       string
+	fallbackTeam string
+	load         int
+}
+
+// reviewerPick is the outcome of a reviewer selection: the picked reviewers,
+// the candidates skipped for capacity, the number of slots to be queued and
+// the number of slots nobody could take.
+type reviewerPick struct {
+	reviewers         []string
+	fallbackReviewers map[string]string
+	saturated         []saturatedReviewer
+	queued            int
+	missing           int
+}
+
+func (p *reviewerPick) add(reviewerID string, fallbackTeam string) {
+	p.reviewers = append(p.reviewers, reviewerID)
+	if fallbackTeam == "" {
+		return
+	}
+	if p.fallbackReviewers == nil {
+		p.fallbackReviewers = make(map[string]string)
+	}
+	p.fallbackReviewers[reviewerID] = fallbackTeam
+}
+
+func (p *reviewerPick) saturatedIDs() []string {
+	ids := make([]string, len(p.saturated))
+	for i, reviewer := range p.saturated {
+		ids[i] = reviewer.userID
+	}
+	return ids
+}
+
+// reviewLimits returns the limit of open reviews of every member of the team
+// that has one.
+func reviewLimits(team *m.Team) map[string]int {
+	limits := make(map[string]int)
+	for _, member := range team.Members {
+		if limit, ok := member.ReviewLimit(team.MaxOpenReviews); ok {
+			limits[member.UserID] = limit
+		}
+	}
+	return limits
+}
+
+// splitByCapacity separates the candidates that have reached their limit of
+// open reviews from the rest. The load is only read when a limit applies.
+func (s *prService) splitByCapacity(ctx context.Context, candidates []string, limits map[string]int) ([]string, []saturatedReviewer, error) {
+	if !slices.ContainsFunc(candidates, func(userID string) bool {
+		_, ok := limits[userID]
+		return ok
+	}) {
+		return candidates, nil, nil
+	}
+
+	load, err := getReviewLoad(ctx, s.prRepo, candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var free []string
+	var saturated []saturatedReviewer
+	for _, userID := range candidates {
+		if limit, ok := limits[userID]; ok && load[userID] >= limit {
+			saturated = append(saturated, saturatedReviewer{userID: userID, load: load[userID]})
+			continue
+		}
+		free = append(free, userID)
+	}
+
+	return free, saturated, nil
+}
+
+// applyCapacityPolicy handles the slots left empty because every remaining
+// candidate is at capacity, according to the team's policy: the least loaded
+// of them are over-assigned, the slots are queued, or the selection fails.
+func applyCapacityPolicy(team *m.Team, pick *reviewerPick, count int) error {
+	const method = "services.applyCapacityPolicy"
+
+	missing := count - len(pick.reviewers)
+	if missing <= 0 || len(pick.saturated) == 0 {
+		return nil
+	}
+	missing = min(missing, len(pick.saturated))
+
+	metrics.CapacityExhausted.WithLabelValues(team.TeamName, string(team.CapacityPolicy)).Inc()
+
+	switch team.CapacityPolicy {
+	case m.CapacityFail:
+		slog.Error("all candidates are at capacity",
+			"method", method,
+			"team_name", team.TeamName,
+			"missing", missing,
+		)
+		return errors.ErrNoCapacity
+	case m.CapacityLeaveUnfilled:
+		slog.Info("review slots left unfilled, all candidates are at capacity",
+			"method", method,
+			"team_name", team.TeamName,
+			"queued", missing,
+		)
+		pick.queued = missing
+		return nil
+	}
+
+	overflow := slices.Clone(pick.saturated)
+	slices.SortStableFunc(overflow, func(a, b saturatedReviewer) int {
+		return cmp.Compare(a.load, b.load)
+	})
+	for _, reviewer := range overflow[:missing] {
+		pick.add(reviewer.userID, reviewer.fallbackTeam)
+	}
+
+	slog.Info("reviewers over-assigned, all candidates are at capacity",
+		"method", method,
+		"team_name", team.TeamName,
+		"over_assigned", missing,
+	)
+	return nil
+}
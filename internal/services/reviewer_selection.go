@@ -0,0 +1,191 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"math/rand"
+	"slices"
+
+	"github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+const defaultSelectionStrategy = m.StrategyLeastLoaded
+
+type SelectionRequest struct {
+	Team       *m.Team
+	Candidates []string
+	Count      int
+}
+
+// ReviewerSelectionStrategy picks up to request.Count reviewers from the
+// already filtered request.Candidates.
+type ReviewerSelectionStrategy interface {
+	Select(ctx context.Context, request SelectionRequest) ([]string, error)
+}
+
+type ReviewerStrategies map[m.SelectionStrategy]ReviewerSelectionStrategy
+
+func NewReviewerStrategies(prRepo repo.PRRepository, teamRepo repo.TeamRepository) ReviewerStrategies {
+	return ReviewerStrategies{
+		m.StrategyRandom:      &randomStrategy{},
+		m.StrategyRoundRobin:  &roundRobinStrategy{teamRepo: teamRepo},
+		m.StrategyLeastLoaded: &leastLoadedStrategy{prRepo: prRepo},
+		m.StrategyWeighted:    &weightedStrategy{prRepo: prRepo},
+	}
+}
+
+func (s ReviewerStrategies) For(team *m.Team) ReviewerSelectionStrategy {
+	if strategy, ok := s[team.SelectionStrategy]; ok {
+		return strategy
+	}
+	return s[defaultSelectionStrategy]
+}
+
+type randomStrategy struct{}
+
+func (s *randomStrategy) Select(_ context.Context, request SelectionRequest) ([]string, error) {
+	perm := rand.Perm(len(request.Candidates))
+	count := min(request.Count, len(perm))
+
+	selected := make([]string, count)
+	for i := range count {
+		selected[i] = request.Candidates[perm[i]]
+	}
+
+	return selected, nil
+}
+
+// roundRobinStrategy walks the team members in user_id order starting right
+// after the user stored in the team's persisted rotation cursor. The order does
+// not depend on names or on who is still in the team, so the rotation resumes
+// at the next member even when the cursor user has left. It must run inside a
+// transaction so that the cursor row stays locked until commit.
+type roundRobinStrategy struct {
+	teamRepo repo.TeamRepository
+}
+
+func (s *roundRobinStrategy) Select(ctx context.Context, request SelectionRequest) ([]string, error) {
+	teamName := request.Team.TeamName
+
+	cursor, err := s.teamRepo.GetRotationCursorForUpdate(ctx, teamName)
+	if err != nil {
+		return nil, errors.WrapInternal(err, "failed to get rotation cursor")
+	}
+
+	members := slices.SortedFunc(slices.Values(request.Team.Members), func(a, b m.TeamMember) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	start := 0
+	if cursor != nil {
+		position, found := slices.BinarySearchFunc(members, *cursor, func(member m.TeamMember, userID string) int {
+			return cmp.Compare(member.UserID, userID)
+		})
+		start = position
+		if found {
+			start++
+		}
+	}
+
+	var selected []string
+	for i := range members {
+		if len(selected) == request.Count {
+			break
+		}
+		userID := members[(start+i)%len(members)].UserID
+		if slices.Contains(request.Candidates, userID) {
+			selected = append(selected, userID)
+		}
+	}
+
+	if len(selected) > 0 {
+		if err := s.teamRepo.UpdateRotationCursor(ctx, teamName, selected[len(selected)-1]); err != nil {
+			return nil, errors.WrapInternal(err, "failed to update rotation cursor")
+		}
+	}
+
+	return selected, nil
+}
+
+type leastLoadedStrategy struct {
+	prRepo repo.PRRepository
+}
+
+func (s *leastLoadedStrategy) Select(ctx context.Context, request SelectionRequest) ([]string, error) {
+	load, err := getReviewLoad(ctx, s.prRepo, request.Candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	shuffled := slices.Clone(request.Candidates)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	slices.SortStableFunc(shuffled, func(a, b string) int {
+		return cmp.Compare(load[a], load[b])
+	})
+
+	if len(shuffled) > request.Count {
+		shuffled = shuffled[:request.Count]
+	}
+
+	return shuffled, nil
+}
+
+// weightedStrategy draws candidates randomly with a probability inversely
+// proportional to the number of their OPEN review assignments.
+type weightedStrategy struct {
+	prRepo repo.PRRepository
+}
+
+func (s *weightedStrategy) Select(ctx context.Context, request SelectionRequest) ([]string, error) {
+	load, err := getReviewLoad(ctx, s.prRepo, request.Candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := slices.Clone(request.Candidates)
+	var selected []string
+
+	for len(remaining) > 0 && len(selected) < request.Count {
+		weights := make([]float64, len(remaining))
+		var total float64
+		for i, userID := range remaining {
+			weights[i] = 1 / float64(1+load[userID])
+			total += weights[i]
+		}
+
+		point := rand.Float64() * total
+		picked := len(remaining) - 1
+		for i, weight := range weights {
+			if point < weight {
+				picked = i
+				break
+			}
+			point -= weight
+		}
+
+		selected = append(selected, remaining[picked])
+		remaining = slices.Delete(remaining, picked, picked+1)
+	}
+
+	return selected, nil
+}
+
+func getReviewLoad(ctx context.Context, prRepo repo.PRRepository, userIDs []string) (map[string]int, error) {
+	const method = "services.getReviewLoad"
+
+	load, err := prRepo.GetOpenReviewLoad(ctx, userIDs)
+	if err != nil {
+		slog.Error("failed to get reviewers load",
+			"method", method,
+			"user_ids", userIDs,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to get reviewers load")
+	}
+
+	return load, nil
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+// fakeLoadRepo serves open review counts from a map. Other PRRepository
+// methods are not used by the strategies and panic if called.
+type fakeLoadRepo struct {
+	repo.PRRepository
+	load map[string]int
+}
+
+func (r *fakeLoadRepo) GetOpenReviewLoad(_ context.Context, userIDs []string) (map[string]int, error) {
+	load := make(map[string]int, len(userIDs))
+	for _, userID := range userIDs {
+		if count, ok := r.load[userID]; ok {
+			load[userID] = count
+		}
+	}
+	return load, nil
+}
+
+func TestLeastLoadedStrategySelect(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		load       map[string]int
+		count      int
+		want       []string
+	}{
+		{
+			name:       "picks the least loaded candidates in order",
+			candidates: []string{"u1", "u2", "u3", "u4"},
+			load:       map[string]int{"u1": 5, "u2": 1, "u3": 3, "u4": 2},
+			count:      2,
+			want:       []string{"u2", "u4"},
+		},
+		{
+			name:       "candidates without open reviews come first",
+			candidates: []string{"u1", "u2", "u3"},
+			load:       map[string]int{"u1": 2, "u3": 1},
+			count:      1,
+			want:       []string{"u2"},
+		},
+		{
+			name:       "count above the number of candidates returns all of them",
+			candidates: []string{"u1", "u2"},
+			load:       map[string]int{"u1": 4, "u2": 0},
+			count:      3,
+			want:       []string{"u2", "u1"},
+		},
+		{
+			name:       "no candidates",
+			candidates: []string{},
+			load:       map[string]int{},
+			count:      2,
+			want:       []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := &leastLoadedStrategy{prRepo: &fakeLoadRepo{load: tt.load}}
+
+			got, err := strategy.Select(context.Background(), SelectionRequest{
+				Team:       &m.Team{TeamName: "backend"},
+				Candidates: tt.candidates,
+				Count:      tt.count,
+			})
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeastLoadedStrategyBreaksTiesRandomly(t *testing.T) {
+	candidates := []string{"u1", "u2", "u3", "u4"}
+	load := map[string]int{"u1": 1, "u2": 1, "u3": 1, "u4": 3}
+	strategy := &leastLoadedStrategy{prRepo: &fakeLoadRepo{load: load}}
+
+	const runs = 3000
+	picked := make(map[string]int)
+	for range runs {
+		got, err := strategy.Select(context.Background(), SelectionRequest{
+			Team:       &m.Team{TeamName: "backend"},
+			Candidates: candidates,
+			Count:      1,
+		})
+		if err != nil {
+			t.Fatalf("Select() error = %v", err)
+		}
+		picked[got[0]]++
+	}
+
+	if picked["u4"] != 0 {
+		t.Errorf("more loaded candidate u4 picked %d times over tied less loaded ones", picked["u4"])
+	}
+	// Each of the three tied candidates is expected runs/3 times; a share
+	// below a quarter would mean ties are broken by a fixed order.
+	for _, userID := range []string{"u1", "u2", "u3"} {
+		if picked[userID] < runs/4 {
+			t.Errorf("tied candidate %s picked %d of %d times, ties are not broken evenly", userID, picked[userID], runs)
+		}
+	}
+}
+
+// TestLeastLoadedStrategyFlattensDistribution assigns reviews one by one and
+// compares the resulting spread of open reviews with the RANDOM strategy.
+func TestLeastLoadedStrategyFlattensDistribution(t *testing.T) {
+	candidates := []string{"u1", "u2", "u3", "u4", "u5"}
+	const assignments = 200
+
+	spread := func(load map[string]int) int {
+		counts := slices.Collect(maps.Values(load))
+		return slices.Max(counts) - slices.Min(counts)
+	}
+
+	simulate := func(strategy func(*fakeLoadRepo) ReviewerSelectionStrategy) (int, int) {
+		loadRepo := &fakeLoadRepo{load: make(map[string]int)}
+		for _, userID := range candidates {
+			loadRepo.load[userID] = 0
+		}
+		selector := strategy(loadRepo)
+
+		maxSpread := 0
+		for range assignments {
+			got, err := selector.Select(context.Background(), SelectionRequest{
+				Team:       &m.Team{TeamName: "backend"},
+				Candidates: candidates,
+				Count:      1,
+			})
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			loadRepo.load[got[0]]++
+			maxSpread = max(maxSpread, spread(loadRepo.load))
+		}
+		return spread(loadRepo.load), maxSpread
+	}
+
+	leastLoadedSpread, leastLoadedMax := simulate(func(loadRepo *fakeLoadRepo) ReviewerSelectionStrategy {
+		return &leastLoadedStrategy{prRepo: loadRepo}
+	})
+	randomSpread, _ := simulate(func(*fakeLoadRepo) ReviewerSelectionStrategy {
+		return &randomStrategy{}
+	})
+
+	if leastLoadedMax > 1 {
+		t.Errorf("LEAST_LOADED spread reached %d, want at most 1 at any time", leastLoadedMax)
+	}
+	if leastLoadedSpread != 0 {
+		t.Errorf("LEAST_LOADED final spread = %d, want 0 for %d reviews over %d candidates", leastLoadedSpread, assignments, len(candidates))
+	}
+	if leastLoadedSpread > randomSpread {
+		t.Errorf("LEAST_LOADED spread %d is wider than RANDOM spread %d", leastLoadedSpread, randomSpread)
+	}
+}
+
+// fakeCursorRepo keeps the rotation cursor of a single team in memory.
+type fakeCursorRepo struct {
+	repo.TeamRepository
+	cursor *string
+}
+
+func (r *fakeCursorRepo) GetRotationCursorForUpdate(_ context.Context, _ string) (*string, error) {
+	return r.cursor, nil
+}
+
+func (r *fakeCursorRepo) UpdateRotationCursor(_ context.Context, _ string, userID string) error {
+	r.cursor = &userID
+	return nil
+}
+
+func TestRoundRobinStrategySelect(t *testing.T) {
+	cursor := func(userID string) *string { return &userID }
+	// Members come from the repository ordered by name, not by user_id.
+	members := []m.TeamMember{
+		{UserID: "u3", Username: "Alice"},
+		{UserID: "u1", Username: "Bob"},
+		{UserID: "u4", Username: "Carol"},
+		{UserID: "u2", Username: "Dave"},
+	}
+
+	tests := []struct {
+		name       string
+		cursor     *string
+		candidates []string
+		count      int
+		want       []string
+		wantCursor string
+	}{
+		{
+			name:       "starts at the first member without a cursor",
+			candidates: []string{"u1", "u2", "u3", "u4"},
+			count:      1,
+			want:       []string{"u1"},
+			wantCursor: "u1",
+		},
+		{
+			name:       "continues right after the cursor",
+			cursor:     cursor("u2"),
+			candidates: []string{"u1", "u2", "u3", "u4"},
+			count:      2,
+			want:       []string{"u3", "u4"},
+			wantCursor: "u4",
+		},
+		{
+			name:       "wraps around after the last member",
+			cursor:     cursor("u4"),
+			candidates: []string{"u1", "u2", "u3", "u4"},
+			count:      2,
+			want:       []string{"u1", "u2"},
+			wantCursor: "u2",
+		},
+		{
+			name:       "skips members that are not candidates",
+			cursor:     cursor("u1"),
+			candidates: []string{"u1", "u4"},
+			count:      1,
+			want:       []string{"u4"},
+			wantCursor: "u4",
+		},
+		{
+			name:       "cursor user is inactive",
+			cursor:     cursor("u2"),
+			candidates: []string{"u1", "u3", "u4"},
+			count:      1,
+			want:       []string{"u3"},
+			wantCursor: "u3",
+		},
+		{
+			name:       "cursor user has left the team",
+			cursor:     cursor("u25"),
+			candidates: []string{"u1", "u2", "u3", "u4"},
+			count:      1,
+			want:       []string{"u3"},
+			wantCursor: "u3",
+		},
+		{
+			name:       "cursor user past the last member has left the team",
+			cursor:     cursor("u9"),
+			candidates: []string{"u1", "u2", "u3", "u4"},
+			count:      1,
+			want:       []string{"u1"},
+			wantCursor: "u1",
+		},
+		{
+			name:       "no candidates keeps the cursor",
+			cursor:     cursor("u2"),
+			candidates: []string{},
+			count:      1,
+			want:       nil,
+			wantCursor: "u2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursorRepo := &fakeCursorRepo{cursor: tt.cursor}
+			strategy := &roundRobinStrategy{teamRepo: cursorRepo}
+
+			got, err := strategy.Select(context.Background(), SelectionRequest{
+				Team:       &m.Team{TeamName: "backend", Members: members},
+				Candidates: tt.candidates,
+				Count:      tt.count,
+			})
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+			if cursorRepo.cursor == nil || *cursorRepo.cursor != tt.wantCursor {
+				t.Errorf("cursor = %v, want %s", cursorRepo.cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestRoundRobinStrategyRotatesEvenly(t *testing.T) {
+	members := []m.TeamMember{{UserID: "u1"}, {UserID: "u2"}, {UserID: "u3"}}
+	candidates := []string{"u1", "u2", "u3"}
+	strategy := &roundRobinStrategy{teamRepo: &fakeCursorRepo{}}
+
+	picked := make(map[string]int)
+	for range 30 {
+		got, err := strategy.Select(context.Background(), SelectionRequest{
+			Team:       &m.Team{TeamName: "backend", Members: members},
+			Candidates: candidates,
+			Count:      1,
+		})
+		if err != nil {
+			t.Fatalf("Select() error = %v", err)
+		}
+		picked[got[0]]++
+	}
+
+	for _, userID := range candidates {
+		if picked[userID] != 10 {
+			t.Errorf("%s picked %d times, want 10", userID, picked[userID])
+		}
+	}
+}
+
+// TestRoundRobinStrategyResumesAfterCursorHolderLeaves removes the member the
+// cursor points at, which keeps the cursor, and expects the rotation to go on
+// with the next member instead of starting over.
+func TestRoundRobinStrategyResumesAfterCursorHolderLeaves(t *testing.T) {
+	members := []m.TeamMember{{UserID: "u1"}, {UserID: "u2"}, {UserID: "u3"}, {UserID: "u4"}}
+	cursorRepo := &fakeCursorRepo{}
+	strategy := &roundRobinStrategy{teamRepo: cursorRepo}
+
+	selectOne := func(members []m.TeamMember) string {
+		t.Helper()
+		candidates := make([]string, len(members))
+		for i, member := range members {
+			candidates[i] = member.UserID
+		}
+		got, err := strategy.Select(context.Background(), SelectionRequest{
+			Team:       &m.Team{TeamName: "backend", Members: members},
+			Candidates: candidates,
+			Count:      1,
+		})
+		if err != nil {
+			t.Fatalf("Select() error = %v", err)
+		}
+		return got[0]
+	}
+
+	var picked []string
+	for range 2 {
+		picked = append(picked, selectOne(members))
+	}
+
+	// u2 holds the cursor and leaves the team
+	remaining := slices.DeleteFunc(slices.Clone(members), func(member m.TeamMember) bool {
+		return member.UserID == "u2"
+	})
+	for range 3 {
+		picked = append(picked, selectOne(remaining))
+	}
+
+	if want := []string{"u1", "u2", "u3", "u4", "u1"}; !slices.Equal(picked, want) {
+		t.Errorf("picked %v, want %v", picked, want)
+	}
+}
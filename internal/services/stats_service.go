@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jonx8/pr-review-service/internal/errors"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	repo "github.com/jonx8/pr-review-service/internal/repositories"
+)
+
+type StatsService interface {
+	GetReviewerStats(ctx context.Context, filter m.ReviewerStatsFilter) ([]m.ReviewerStats, error)
+}
+
+type statsService struct {
+	statsRepo   repo.StatsRepository
+	teamService TeamService
+}
+
+func NewStatsService(statsRepo repo.StatsRepository, teamService TeamService) StatsService {
+	return &statsService{
+		statsRepo:   statsRepo,
+		teamService: teamService,
+	}
+}
+
+func (s *statsService) GetReviewerStats(ctx context.Context, filter m.ReviewerStatsFilter) ([]m.ReviewerStats, error) {
+	const method = "StatsService.GetReviewerStats"
+
+	if filter.TeamName != nil {
+		if _, err := s.teamService.GetTeam(ctx, *filter.TeamName); err != nil {
+			return nil, err
+		}
+	}
+
+	stats, err := s.statsRepo.GetReviewerStats(ctx, filter)
+	if err != nil {
+		slog.Error("failed to get reviewer stats",
+			"method", method,
+			"error", err,
+		)
+		return nil, errors.WrapInternal(err, "failed to get reviewer stats")
+	}
+
+	return stats, nil
+}
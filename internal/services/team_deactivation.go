@@ -0,0 +1,674 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/jonx8/pr-review-service/internal/errors"
+	"github.com/jonx8/pr-review-service/internal/metrics"
+	m "github.com/jonx8/pr-review-service/internal/models"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// candidatePool is a group of reviewers that can take over reviews, ordered
+// by preference: the home team first, then fallback teams by priority. limits
+// holds the limit of open reviews of the reviewers that have one.
+type candidatePool struct {
+	teamName  string
+	fallback  bool
+	reviewers []string
+	limits    map[string]int
+}
+
+// DeactivateTeam deactivates the requested members of a team, or all of them,
+// and redistributes their open reviews in one transaction. Replacements are
+// chosen by least load among the remaining active members of each PR's team
+// and its fallback teams, and are written with a constant number of
+// statements.
+func (s *prService) DeactivateTeam(ctx context.Context, request m.DeactivateTeamRequest) (*m.TeamDeactivationReport, error) {
+	const method = "PRService.DeactivateTeam"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("team_name", request.TeamName))
+	defer span.End()
+
+	var report *m.TeamDeactivationReport
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		team, err := s.teamService.GetTeam(ctx, request.TeamName)
+		if err != nil {
+			return err
+		}
+
+		targets, err := deactivationTargets(team, request.UserIDs)
+		if err != nil {
+			return err
+		}
+
+		deactivated, err := s.userService.DeactivateUsers(ctx, targets)
+		if err != nil {
+			return err
+		}
+
+		report = &m.TeamDeactivationReport{
+			TeamName:    team.TeamName,
+			Deactivated: make([]string, len(deactivated)),
+			NoCandidate: []m.UnfilledReview{},
+			Queued:      []m.UnfilledReview{},
+		}
+		for i, user := range deactivated {
+			report.Deactivated[i] = user.UserID
+		}
+
+		reassigned, unfilled, queued, err := s.reassignOpenReviews(ctx, targets, "", m.ReasonDeactivation)
+		if err != nil {
+			return err
+		}
+
+		report.ReassignedCount = reassigned
+		report.NoCandidate = append(report.NoCandidate, unfilled...)
+		report.Queued = append(report.Queued, queued...)
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	metrics.Reassignments.WithLabelValues(string(m.ReasonDeactivation)).Add(float64(report.ReassignedCount))
+	metrics.NoCandidateFailures.WithLabelValues(report.TeamName).Add(float64(len(report.NoCandidate)))
+
+	slog.Info("team deactivated",
+		"method", method,
+		"team_name", report.TeamName,
+		"deactivated", len(report.Deactivated),
+		"reassigned", report.ReassignedCount,
+		"no_candidate", len(report.NoCandidate),
+		"queued", len(report.Queued),
+	)
+
+	return report, nil
+}
+
+// RemoveTeamMember hands the member's open reviews of the team's PRs over to
+// the remaining active members of the team and its fallback teams, the same
+// way as DeactivateTeam does, and then detaches the member from the team. The
+// user is kept, so their PRs, reviews in other teams and review history stay
+// intact.
+func (s *prService) RemoveTeamMember(ctx context.Context, request m.RemoveTeamMemberRequest) (*m.TeamMemberRemovalReport, error) {
+	const method = "PRService.RemoveTeamMember"
+
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("team_name", request.TeamName),
+		attribute.String("user_id", request.UserID),
+	)
+	defer span.End()
+
+	var report *m.TeamMemberRemovalReport
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		team, err := s.teamService.GetTeam(ctx, request.TeamName)
+		if err != nil {
+			return err
+		}
+
+		if !slices.ContainsFunc(team.Members, func(member m.TeamMember) bool {
+			return member.UserID == request.UserID
+		}) {
+			return errors.ErrNotTeamMember
+		}
+
+		reassigned, unfilled, queued, err := s.reassignOpenReviews(ctx, []string{request.UserID}, team.TeamName, m.ReasonRemoval)
+		if err != nil {
+			return err
+		}
+
+		if err := s.teamService.RemoveMember(ctx, team.TeamName, request.UserID); err != nil {
+			return err
+		}
+
+		report = &m.TeamMemberRemovalReport{
+			TeamName:        team.TeamName,
+			UserID:          request.UserID,
+			ReassignedCount: reassigned,
+			NoCandidate:     append([]m.UnfilledReview{}, unfilled...),
+			Queued:          append([]m.UnfilledReview{}, queued...),
+		}
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	metrics.Reassignments.WithLabelValues(string(m.ReasonRemoval)).Add(float64(report.ReassignedCount))
+	metrics.NoCandidateFailures.WithLabelValues(report.TeamName).Add(float64(len(report.NoCandidate)))
+
+	slog.Info("team member removed",
+		"method", method,
+		"team_name", report.TeamName,
+		"user_id", report.UserID,
+		"reassigned", report.ReassignedCount,
+		"no_candidate", len(report.NoCandidate),
+		"queued", len(report.Queued),
+	)
+
+	return report, nil
+}
+
+// MoveTeamMember moves the user to another team with TeamService.MoveMember
+// and hands their open reviews of the PRs of the team they left over, the same
+// way as RemoveTeamMember does.
+func (s *prService) MoveTeamMember(ctx context.Context, request m.MoveTeamRequest) (*m.User, error) {
+	const method = "PRService.MoveTeamMember"
+
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("team_name", request.TeamName),
+		attribute.String("user_id", request.UserID),
+	)
+	defer span.End()
+
+	var movedUser *m.User
+	var reassigned int
+	var unfilled, queued []m.UnfilledReview
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		user, err := s.userService.GetUser(ctx, request.UserID)
+		if err != nil {
+			return err
+		}
+		if request.FromTeam == "" && len(user.Teams) == 1 {
+			request.FromTeam = user.PrimaryTeam()
+		}
+
+		movedUser, err = s.teamService.MoveMember(ctx, request)
+		if err != nil || request.FromTeam == "" {
+			return err
+		}
+
+		reassigned, unfilled, queued, err = s.reassignOpenReviews(ctx, []string{request.UserID}, request.FromTeam, m.ReasonRemoval)
+		return err
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	if request.FromTeam != "" {
+		metrics.Reassignments.WithLabelValues(string(m.ReasonRemoval)).Add(float64(reassigned))
+		metrics.NoCandidateFailures.WithLabelValues(request.FromTeam).Add(float64(len(unfilled)))
+	}
+
+	slog.Info("reviews of moved user reassigned",
+		"method", method,
+		"user_id", request.UserID,
+		"from_team", request.FromTeam,
+		"reassigned", reassigned,
+		"no_candidate", len(unfilled),
+		"queued", len(queued),
+	)
+
+	return movedUser, nil
+}
+
+// CreateTeam creates the team with TeamService.CreateTeam. With move_members
+// set, the members leave their other teams, so their open reviews of those
+// teams' PRs are handed over the same way as RemoveTeamMember does.
+func (s *prService) CreateTeam(ctx context.Context, team *m.Team) (*m.Team, error) {
+	const method = "PRService.CreateTeam"
+
+	if !team.MoveMembers {
+		return s.teamService.CreateTeam(ctx, team)
+	}
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("team_name", team.TeamName))
+	defer span.End()
+
+	var createdTeam *m.Team
+	leavers := make(map[string][]string)
+	var leftTeams []string
+	reassigned := make(map[string]int)
+	unfilled := make(map[string]int)
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		for _, member := range team.Members {
+			user, err := s.userService.GetUser(ctx, member.UserID)
+			if err == errors.ErrUserNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			for _, teamName := range user.Teams {
+				if _, ok := leavers[teamName]; !ok {
+					leftTeams = append(leftTeams, teamName)
+				}
+				leavers[teamName] = append(leavers[teamName], user.UserID)
+			}
+		}
+
+		var err error
+		createdTeam, err = s.teamService.CreateTeam(ctx, team)
+		if err != nil {
+			return err
+		}
+
+		for _, teamName := range leftTeams {
+			teamReassigned, teamUnfilled, teamQueued, err := s.reassignOpenReviews(ctx, leavers[teamName], teamName, m.ReasonRemoval)
+			if err != nil {
+				return err
+			}
+			reassigned[teamName] = teamReassigned
+			unfilled[teamName] = len(teamUnfilled)
+
+			slog.Info("reviews of members moved to a new team reassigned",
+				"method", method,
+				"team_name", team.TeamName,
+				"from_team", teamName,
+				"reassigned", teamReassigned,
+				"no_candidate", len(teamUnfilled),
+				"queued", len(teamQueued),
+			)
+		}
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	for _, teamName := range leftTeams {
+		metrics.Reassignments.WithLabelValues(string(m.ReasonRemoval)).Add(float64(reassigned[teamName]))
+		metrics.NoCandidateFailures.WithLabelValues(teamName).Add(float64(unfilled[teamName]))
+	}
+
+	return createdTeam, nil
+}
+
+// ReassignAbsentReviews hands all open reviews of an absent user over to the
+// available members of each PR's team and its fallback teams, the same way as
+// DeactivateTeam does. Like reassignOpenReviews, it returns the number of
+// reviews handed over, the reviews left without a candidate and the queued
+// ones.
+func (s *prService) ReassignAbsentReviews(ctx context.Context, userID string) (int, []m.UnfilledReview, []m.UnfilledReview, error) {
+	const method = "PRService.ReassignAbsentReviews"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
+	var reassigned int
+	var unfilled, queued []m.UnfilledReview
+	err := s.trManager.Do(ctx, func(ctx context.Context) error {
+		var err error
+		reassigned, unfilled, queued, err = s.reassignOpenReviews(ctx, []string{userID}, "", m.ReasonAbsence)
+		return err
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, nil, nil, err
+	}
+
+	metrics.Reassignments.WithLabelValues(string(m.ReasonAbsence)).Add(float64(reassigned))
+
+	slog.Info("reviews of absent user reassigned",
+		"method", method,
+		"user_id", userID,
+		"reassigned", reassigned,
+		"no_candidate", len(unfilled),
+		"queued", len(queued),
+	)
+
+	return reassigned, unfilled, queued, nil
+}
+
+// reassignOpenReviews redistributes the open reviews of userIDs on the PRs of
+// teamName, or on all PRs when it is empty. Each review goes to the least
+// loaded active member of the PR's team, or the author's primary team for PRs
+// without one, or its fallback teams that is not in userIDs and has spare
+// capacity. It returns the number of reviews handed over,
+// the reviews left without a candidate and the reviews whose replacement was
+// queued. When only candidates at capacity remain, the PR team's capacity
+// policy applies: OVER_ASSIGN hands the review over anyway, LEAVE_UNFILLED
+// queues the replacement and the other policies leave the review where it is.
+func (s *prService) reassignOpenReviews(ctx context.Context, userIDs []string, teamName string, reason m.AssignmentReason) (int, []m.UnfilledReview, []m.UnfilledReview, error) {
+	const method = "PRService.reassignOpenReviews"
+
+	assignments, err := s.prRepo.GetOpenAssignments(ctx, userIDs)
+	if err != nil {
+		slog.Error("failed to get open assignments",
+			"method", method,
+			"team_name", teamName,
+			"error", err,
+		)
+		return 0, nil, nil, errors.WrapInternal(err, "failed to get open assignments")
+	}
+	reviewTeams, err := s.assignmentTeams(ctx, assignments)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if teamName != "" {
+		assignments = slices.DeleteFunc(assignments, func(assignment m.OpenAssignment) bool {
+			return reviewTeams[assignment.PullRequestID] != teamName
+		})
+	}
+	if len(assignments) == 0 {
+		return 0, nil, nil, nil
+	}
+
+	var unfilled []m.UnfilledReview
+	byTeam := make(map[string][]m.OpenAssignment)
+	var teamNames []string
+	for _, assignment := range assignments {
+		reviewTeam := reviewTeams[assignment.PullRequestID]
+		if reviewTeam == "" {
+			unfilled = append(unfilled, m.UnfilledReview{
+				PullRequestID: assignment.PullRequestID,
+				ReviewerID:    assignment.ReviewerID,
+			})
+			continue
+		}
+		if _, ok := byTeam[reviewTeam]; !ok {
+			teamNames = append(teamNames, reviewTeam)
+		}
+		byTeam[reviewTeam] = append(byTeam[reviewTeam], assignment)
+	}
+
+	pools := make(map[string][]candidatePool, len(teamNames))
+	policies := make(map[string]m.CapacityPolicy, len(teamNames))
+	var candidates []string
+	for _, name := range teamNames {
+		team, err := s.teamService.GetTeam(ctx, name)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		policies[name] = team.CapacityPolicy
+
+		pools[name], err = s.candidatePools(ctx, team, userIDs)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		for _, pool := range pools[name] {
+			candidates = append(candidates, pool.reviewers...)
+		}
+	}
+
+	load, err := s.prRepo.GetOpenReviewLoad(ctx, candidates)
+	if err != nil {
+		slog.Error("failed to get review load",
+			"method", method,
+			"team_name", teamName,
+			"error", err,
+		)
+		return 0, nil, nil, errors.WrapInternal(err, "failed to get review load")
+	}
+
+	var replacements []m.ReviewerReplacement
+	var queued []m.UnfilledReview
+	var queuedReviews []m.QueuedReview
+	actorID := u.ActorFromContext(ctx)
+	for _, name := range teamNames {
+		teamReplacements, teamUnfilled, teamQueued := redistributeReviews(byTeam[name], pools[name], load, policies[name])
+		replacements = append(replacements, teamReplacements...)
+		unfilled = append(unfilled, teamUnfilled...)
+		queued = append(queued, teamQueued...)
+		for _, review := range teamQueued {
+			queuedReviews = append(queuedReviews, m.QueuedReview{
+				PullRequestID:      review.PullRequestID,
+				ReplacesReviewerID: &review.ReviewerID,
+				Reason:             reason,
+				ActorID:            actorID,
+			})
+		}
+	}
+
+	if err := s.applyReplacements(ctx, assignments, replacements, reason); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if err := s.prRepo.QueueReviews(ctx, queuedReviews); err != nil {
+		slog.Error("failed to queue reviews",
+			"method", method,
+			"team_name", teamName,
+			"reviews_count", len(queuedReviews),
+			"error", err,
+		)
+		return 0, nil, nil, errors.WrapInternal(err, "failed to queue reviews")
+	}
+
+	return len(replacements), unfilled, queued, nil
+}
+
+// assignmentTeams maps the PR of each assignment to the team its reviewers
+// come from: the PR's team, or the author's primary team for PRs without one.
+// PRs whose author has no team map to an empty name.
+func (s *prService) assignmentTeams(ctx context.Context, assignments []m.OpenAssignment) (map[string]string, error) {
+	teams := make(map[string]string, len(assignments))
+	authorTeams := make(map[string]string)
+	for _, assignment := range assignments {
+		if assignment.TeamName != "" {
+			teams[assignment.PullRequestID] = assignment.TeamName
+			continue
+		}
+
+		authorTeam, ok := authorTeams[assignment.AuthorID]
+		if !ok {
+			team, err := s.reviewTeam(ctx, &m.PullRequest{AuthorID: assignment.AuthorID})
+			if err != nil && err != errors.ErrUserHasNoTeam {
+				return nil, err
+			}
+			if team != nil {
+				authorTeam = team.TeamName
+			}
+			authorTeams[assignment.AuthorID] = authorTeam
+		}
+		teams[assignment.PullRequestID] = authorTeam
+	}
+	return teams, nil
+}
+
+func deactivationTargets(team *m.Team, userIDs []string) ([]string, error) {
+	members := make([]string, len(team.Members))
+	for i, member := range team.Members {
+		members[i] = member.UserID
+	}
+
+	if len(userIDs) == 0 {
+		return members, nil
+	}
+
+	for _, userID := range userIDs {
+		if !slices.Contains(members, userID) {
+			return nil, errors.NewNotFound(fmt.Sprintf("user %s is not a member of team %s", userID, team.TeamName))
+		}
+	}
+
+	return userIDs, nil
+}
+
+// candidatePools collects the available members of the team that are not
+// excluded, followed by the available members of its fallback teams.
+func (s *prService) candidatePools(ctx context.Context, team *m.Team, excluded []string) ([]candidatePool, error) {
+	pools := []candidatePool{{
+		teamName:  team.TeamName,
+		reviewers: availableMembers(team, excluded),
+		limits:    reviewLimits(team),
+	}}
+
+	for _, fallbackName := range team.FallbackTeams {
+		fallbackTeam, err := s.teamService.GetTeam(ctx, fallbackName)
+		if err != nil {
+			return nil, err
+		}
+
+		pools = append(pools, candidatePool{
+			teamName:  fallbackTeam.TeamName,
+			fallback:  true,
+			reviewers: availableMembers(fallbackTeam, excluded),
+			limits:    reviewLimits(fallbackTeam),
+		})
+	}
+
+	return pools, nil
+}
+
+func availableMembers(team *m.Team, excluded []string) []string {
+	var members []string
+	for _, member := range team.Members {
+		if member.IsAvailable() && !slices.Contains(excluded, member.UserID) {
+			members = append(members, member.UserID)
+		}
+	}
+	return members
+}
+
+// redistributeReviews picks a replacement for every assignment, preferring
+// the least loaded candidate with spare capacity of the first pool that has an
+// eligible one. The load map is updated as reviews are handed out. Reviews
+// without a replacement are returned either as unfilled or, under
+// LEAVE_UNFILLED, as queued, never as both.
+func redistributeReviews(assignments []m.OpenAssignment, pools []candidatePool, load map[string]int, policy m.CapacityPolicy) ([]m.ReviewerReplacement, []m.UnfilledReview, []m.UnfilledReview) {
+	reviewers := make(map[string][]string)
+	for _, assignment := range assignments {
+		if _, ok := reviewers[assignment.PullRequestID]; !ok {
+			reviewers[assignment.PullRequestID] = slices.Clone(assignment.Reviewers)
+		}
+	}
+
+	var replacements []m.ReviewerReplacement
+	var unfilled []m.UnfilledReview
+	var queued []m.UnfilledReview
+	for _, assignment := range assignments {
+		excluded := append([]string{assignment.AuthorID}, reviewers[assignment.PullRequestID]...)
+		review := m.UnfilledReview{
+			PullRequestID: assignment.PullRequestID,
+			ReviewerID:    assignment.ReviewerID,
+		}
+
+		replacement, pool := leastLoadedCandidate(pools, load, excluded, false)
+		if replacement == "" {
+			if overflow, overflowPool := leastLoadedCandidate(pools, load, excluded, true); overflow != "" {
+				metrics.CapacityExhausted.WithLabelValues(pools[0].teamName, string(policy)).Inc()
+				switch policy {
+				case m.CapacityOverAssign:
+					replacement, pool = overflow, overflowPool
+				case m.CapacityLeaveUnfilled:
+					queued = append(queued, review)
+					continue
+				}
+			}
+		}
+		if replacement == "" {
+			unfilled = append(unfilled, review)
+			continue
+		}
+
+		load[replacement]++
+		reviewers[assignment.PullRequestID] = u.ReplaceInSlice(reviewers[assignment.PullRequestID], assignment.ReviewerID, replacement)
+
+		var fallbackTeam *string
+		if pool.fallback {
+			fallbackTeam = &pool.teamName
+		}
+		replacements = append(replacements, m.ReviewerReplacement{
+			PullRequestID: assignment.PullRequestID,
+			OldReviewerID: assignment.ReviewerID,
+			NewReviewerID: replacement,
+			FallbackTeam:  fallbackTeam,
+		})
+	}
+
+	return replacements, unfilled, queued
+}
+
+// leastLoadedCandidate returns the least loaded eligible candidate of the
+// first pool that has one. Candidates at capacity are only eligible when
+// overCapacity is set.
+func leastLoadedCandidate(pools []candidatePool, load map[string]int, excluded []string, overCapacity bool) (string, *candidatePool) {
+	for i := range pools {
+		best := ""
+		for _, candidate := range pools[i].reviewers {
+			if slices.Contains(excluded, candidate) {
+				continue
+			}
+			if limit, ok := pools[i].limits[candidate]; ok && !overCapacity && load[candidate] >= limit {
+				continue
+			}
+			if best == "" || load[candidate] < load[best] {
+				best = candidate
+			}
+		}
+		if best != "" {
+			return best, &pools[i]
+		}
+	}
+	return "", nil
+}
+
+// applyReplacements stores the replacements, appends them to the assignment
+// history and publishes a reassignment event for each of them.
+func (s *prService) applyReplacements(ctx context.Context, assignments []m.OpenAssignment, replacements []m.ReviewerReplacement, reason m.AssignmentReason) error {
+	const method = "PRService.applyReplacements"
+
+	if err := s.prRepo.ReplaceReviewers(ctx, replacements); err != nil {
+		slog.Error("failed to replace reviewers",
+			"method", method,
+			"replacements_count", len(replacements),
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to replace reviewers")
+	}
+
+	prs := make(map[string]*m.PullRequest)
+	for _, assignment := range assignments {
+		if _, ok := prs[assignment.PullRequestID]; !ok {
+			prs[assignment.PullRequestID] = &m.PullRequest{
+				PullRequestID:     assignment.PullRequestID,
+				PullRequestName:   assignment.PullRequestName,
+				AuthorID:          assignment.AuthorID,
+				TeamName:          assignment.TeamName,
+				Status:            assignment.Status,
+				AssignedReviewers: slices.Clone(assignment.Reviewers),
+			}
+		}
+	}
+
+	actorID := u.ActorFromContext(ctx)
+	history := make([]m.PREvent, len(replacements))
+	events := make([]m.Event, len(replacements))
+	for i, replacement := range replacements {
+		oldReviewerID := replacement.OldReviewerID
+		history[i] = m.PREvent{
+			PullRequestID:      replacement.PullRequestID,
+			EventType:          m.PREventReplaced,
+			ReviewerID:         replacement.NewReviewerID,
+			PreviousReviewerID: &oldReviewerID,
+			FallbackTeam:       replacement.FallbackTeam,
+			ActorID:            actorID,
+			Reason:             reason,
+		}
+
+		pr := prs[replacement.PullRequestID]
+		pr.AssignedReviewers = u.ReplaceInSlice(pr.AssignedReviewers, replacement.OldReviewerID, replacement.NewReviewerID)
+		snapshot := *pr
+		events[i] = m.NewEvent(m.EventReviewerReassigned, m.ReviewerReassignedData{
+			PullRequest:   &snapshot,
+			OldReviewerID: replacement.OldReviewerID,
+			NewReviewerID: replacement.NewReviewerID,
+		})
+	}
+
+	if err := s.prRepo.CreateEvents(ctx, history); err != nil {
+		slog.Error("failed to record reviewer replacements",
+			"method", method,
+			"replacements_count", len(replacements),
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to record reviewer replacements")
+	}
+
+	return s.publisher.Publish(ctx, events...)
+}
@@ -2,27 +2,44 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"github.com/avito-tech/go-transaction-manager/trm/v2/manager"
 	"github.com/jonx8/pr-review-service/internal/errors"
 	m "github.com/jonx8/pr-review-service/internal/models"
 	repo "github.com/jonx8/pr-review-service/internal/repositories"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	u "github.com/jonx8/pr-review-service/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	defaultReviewersRequired = 2
+	defaultApprovalsRequired = 1
 )
 
 type TeamService interface {
 	GetTeam(ctx context.Context, name string) (*m.Team, error)
 	CreateTeam(ctx context.Context, team *m.Team) (*m.Team, error)
+	UpdateTeam(ctx context.Context, request m.UpdateTeamRequest) (*m.Team, error)
+	DeleteTeam(ctx context.Context, name string) error
+	AddMember(ctx context.Context, request m.AddTeamMemberRequest) (*m.Team, error)
+	RemoveMember(ctx context.Context, teamName string, userID string) error
+	MoveMember(ctx context.Context, request m.MoveTeamRequest) (*m.User, error)
+	GetMembershipHistory(ctx context.Context, userID string) ([]m.TeamMembership, error)
 }
 
 type teamService struct {
 	teamRepository repo.TeamRepository
+	userService    UserService
 	trManager      *manager.Manager
 }
 
-func NewTeamService(teamRepository repo.TeamRepository, trManager *manager.Manager) TeamService {
+func NewTeamService(teamRepository repo.TeamRepository, userService UserService, trManager *manager.Manager) TeamService {
 	return &teamService{
 		teamRepository: teamRepository,
+		userService:    userService,
 		trManager:      trManager,
 	}
 }
@@ -30,6 +47,9 @@ func NewTeamService(teamRepository repo.TeamRepository, trManager *manager.Manag
 func (service *teamService) CreateTeam(ctx context.Context, team *m.Team) (*m.Team, error) {
 	const method = "TeamService.CreateTeam"
 
+	ctx, span := tracing.Start(ctx, method, attribute.String("team_name", team.TeamName))
+	defer span.End()
+
 	err := service.trManager.Do(ctx, func(ctx context.Context) error {
 		exists, err := service.teamRepository.ExistsByName(ctx, team.TeamName)
 		if err != nil {
@@ -48,6 +68,42 @@ func (service *teamService) CreateTeam(ctx context.Context, team *m.Team) (*m.Te
 			return errors.ErrTeamExists
 		}
 
+		if err := service.validateFallbackTeams(ctx, team); err != nil {
+			return err
+		}
+
+		memberIDs := make([]string, len(team.Members))
+		for i, member := range team.Members {
+			memberIDs[i] = member.UserID
+		}
+
+		var otherTeams map[string][]string
+		if team.MoveMembers {
+			otherTeams, err = service.teamRepository.GetMemberTeams(ctx, memberIDs)
+			if err != nil {
+				slog.Error("failed to get member teams",
+					"method", method,
+					"team_name", team.TeamName,
+					"error", err,
+				)
+				return errors.WrapInternal(err, "failed to get member teams")
+			}
+		}
+
+		if team.SelectionStrategy == "" {
+			team.SelectionStrategy = m.StrategyLeastLoaded
+		}
+		if team.ReviewersRequired == 0 {
+			team.ReviewersRequired = defaultReviewersRequired
+		}
+		if team.ApprovalsRequired == nil {
+			approvalsRequired := defaultApprovalsRequired
+			team.ApprovalsRequired = &approvalsRequired
+		}
+		if team.CapacityPolicy == "" {
+			team.CapacityPolicy = m.CapacityOverAssign
+		}
+
 		if err := service.teamRepository.CreateTeam(ctx, team); err != nil {
 			slog.Error("failed to create team",
 				"method", method,
@@ -57,10 +113,19 @@ func (service *teamService) CreateTeam(ctx context.Context, team *m.Team) (*m.Te
 			return errors.WrapInternal(err, "failed to create team")
 		}
 
-		return nil
+		for _, userID := range memberIDs {
+			for _, teamName := range otherTeams[userID] {
+				if err := service.leaveTeam(ctx, teamName, userID); err != nil {
+					return err
+				}
+			}
+		}
+
+		return service.recordMembershipChange(ctx, team.TeamName, memberIDs, nil)
 	})
 
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
@@ -70,6 +135,9 @@ func (service *teamService) CreateTeam(ctx context.Context, team *m.Team) (*m.Te
 func (service *teamService) GetTeam(ctx context.Context, name string) (*m.Team, error) {
 	const method = "TeamService.GetTeam"
 
+	ctx, span := tracing.Start(ctx, method, attribute.String("team_name", name))
+	defer span.End()
+
 	team, err := service.teamRepository.GetTeamByName(ctx, name)
 	if err != nil {
 		slog.Error("failed to get team",
@@ -77,6 +145,7 @@ func (service *teamService) GetTeam(ctx context.Context, name string) (*m.Team,
 			"team_name", name,
 			"error", err,
 		)
+		tracing.RecordError(span, err)
 		return nil, errors.WrapInternal(err, "failed to get team")
 	}
 
@@ -90,3 +159,386 @@ func (service *teamService) GetTeam(ctx context.Context, name string) (*m.Team,
 
 	return team, nil
 }
+
+func (service *teamService) UpdateTeam(ctx context.Context, request m.UpdateTeamRequest) (*m.Team, error) {
+	const method = "TeamService.UpdateTeam"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("team_name", request.TeamName))
+	defer span.End()
+
+	var updatedTeam *m.Team
+	err := service.trManager.Do(ctx, func(ctx context.Context) error {
+		team, err := service.GetTeam(ctx, request.TeamName)
+		if err != nil {
+			return err
+		}
+
+		if request.SelectionStrategy != nil {
+			team.SelectionStrategy = *request.SelectionStrategy
+		}
+		if request.ReviewersRequired != nil {
+			team.ReviewersRequired = *request.ReviewersRequired
+		}
+		if request.ApprovalsRequired != nil {
+			team.ApprovalsRequired = request.ApprovalsRequired
+		}
+		if request.MaxOpenReviews != nil {
+			team.MaxOpenReviews = request.MaxOpenReviews
+			if *request.MaxOpenReviews == 0 {
+				team.MaxOpenReviews = nil
+			}
+		}
+		if request.CapacityPolicy != nil {
+			team.CapacityPolicy = *request.CapacityPolicy
+		}
+		if request.FallbackTeams != nil {
+			team.FallbackTeams = request.FallbackTeams
+			if err := service.validateFallbackTeams(ctx, team); err != nil {
+				return err
+			}
+		}
+
+		if err := service.teamRepository.UpdateTeam(ctx, team); err != nil {
+			slog.Error("failed to update team",
+				"method", method,
+				"team_name", team.TeamName,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to update team")
+		}
+
+		updatedTeam = team
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return updatedTeam, nil
+}
+
+// DeleteTeam deletes a team without members. Members have to be removed
+// first, so that their open reviews are handed over.
+func (service *teamService) DeleteTeam(ctx context.Context, name string) error {
+	const method = "TeamService.DeleteTeam"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("team_name", name))
+	defer span.End()
+
+	err := service.trManager.Do(ctx, func(ctx context.Context) error {
+		team, err := service.GetTeam(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		if len(team.Members) > 0 {
+			slog.Warn("cannot delete team with members",
+				"method", method,
+				"team_name", name,
+				"members_count", len(team.Members),
+			)
+			return errors.ErrTeamNotEmpty
+		}
+
+		if err := service.teamRepository.DeleteTeam(ctx, name); err != nil {
+			slog.Error("failed to delete team",
+				"method", method,
+				"team_name", name,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to delete team")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	slog.Info("team deleted",
+		"method", method,
+		"team_name", name,
+	)
+
+	return nil
+}
+
+// AddMember adds a new user to the team or takes in an existing user, who
+// keeps their other teams, name and activity unless is_active is set.
+func (service *teamService) AddMember(ctx context.Context, request m.AddTeamMemberRequest) (*m.Team, error) {
+	const method = "TeamService.AddMember"
+
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("team_name", request.TeamName),
+		attribute.String("user_id", request.UserID),
+	)
+	defer span.End()
+
+	var updatedTeam *m.Team
+	err := service.trManager.Do(ctx, func(ctx context.Context) error {
+		if _, err := service.GetTeam(ctx, request.TeamName); err != nil {
+			return err
+		}
+
+		user, err := service.userService.GetUser(ctx, request.UserID)
+		if err != nil && err != errors.ErrUserNotFound {
+			return err
+		}
+		if user != nil && user.IsMemberOf(request.TeamName) {
+			return errors.ErrAlreadyMember
+		}
+		if user == nil && request.Username == "" {
+			return errors.NewValidation("username is required for a new user")
+		}
+		if user != nil && request.IsActive != nil && *request.IsActive != user.IsActive {
+			if _, err := service.userService.SetIsActive(ctx, m.SetActiveRequest{
+				UserID:   user.UserID,
+				IsActive: *request.IsActive,
+			}); err != nil {
+				return err
+			}
+		}
+
+		member := m.TeamMember{
+			UserID:   request.UserID,
+			Username: request.Username,
+			IsActive: request.IsActive == nil || *request.IsActive,
+		}
+		if err := service.teamRepository.AddMember(ctx, request.TeamName, member); err != nil {
+			slog.Error("failed to add team member",
+				"method", method,
+				"team_name", request.TeamName,
+				"user_id", request.UserID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to add team member")
+		}
+
+		if err := service.recordMembershipChange(ctx, request.TeamName, []string{request.UserID}, nil); err != nil {
+			return err
+		}
+
+		updatedTeam, err = service.GetTeam(ctx, request.TeamName)
+		return err
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return updatedTeam, nil
+}
+
+// RemoveMember detaches the user from the team. Their open reviews are not
+// touched; PRService.RemoveTeamMember hands them over before calling it.
+func (service *teamService) RemoveMember(ctx context.Context, teamName string, userID string) error {
+	const method = "TeamService.RemoveMember"
+
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("team_name", teamName),
+		attribute.String("user_id", userID),
+	)
+	defer span.End()
+
+	user, err := service.userService.GetUser(ctx, userID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	if !user.IsMemberOf(teamName) {
+		return errors.ErrNotTeamMember
+	}
+
+	if err := service.leaveTeam(ctx, teamName, userID); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// MoveMember moves the user from one of their teams to another. FromTeam may
+// be omitted for users with at most one team. Their open reviews are not
+// touched; PRService.MoveTeamMember hands them over around this call. The
+// membership history keeps the previous team.
+func (service *teamService) MoveMember(ctx context.Context, request m.MoveTeamRequest) (*m.User, error) {
+	const method = "TeamService.MoveMember"
+
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("team_name", request.TeamName),
+		attribute.String("user_id", request.UserID),
+	)
+	defer span.End()
+
+	var movedUser *m.User
+	err := service.trManager.Do(ctx, func(ctx context.Context) error {
+		if _, err := service.GetTeam(ctx, request.TeamName); err != nil {
+			return err
+		}
+
+		user, err := service.userService.GetUser(ctx, request.UserID)
+		if err != nil {
+			return err
+		}
+		if user.IsMemberOf(request.TeamName) {
+			return errors.ErrAlreadyMember
+		}
+
+		fromTeam := request.FromTeam
+		if fromTeam == "" {
+			if len(user.Teams) > 1 {
+				return errors.NewValidation("from_team is required for users in several teams")
+			}
+			fromTeam = user.PrimaryTeam()
+		} else if !user.IsMemberOf(fromTeam) {
+			return errors.ErrNotTeamMember
+		}
+
+		if fromTeam == "" {
+			member := m.TeamMember{UserID: user.UserID, Username: user.Username, IsActive: user.IsActive}
+			err = service.teamRepository.AddMember(ctx, request.TeamName, member)
+		} else {
+			err = service.teamRepository.MoveMember(ctx, request.UserID, fromTeam, request.TeamName)
+		}
+		if err != nil {
+			slog.Error("failed to move team member",
+				"method", method,
+				"team_name", request.TeamName,
+				"user_id", request.UserID,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to move team member")
+		}
+
+		if fromTeam != "" {
+			if err := service.recordMembershipChange(ctx, fromTeam, nil, []string{user.UserID}); err != nil {
+				return err
+			}
+		}
+		if err := service.recordMembershipChange(ctx, request.TeamName, []string{user.UserID}, nil); err != nil {
+			return err
+		}
+
+		slog.Info("user moved to another team",
+			"method", method,
+			"user_id", user.UserID,
+			"from_team", fromTeam,
+			"to_team", request.TeamName,
+		)
+
+		movedUser, err = service.userService.GetUser(ctx, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return movedUser, nil
+}
+
+func (service *teamService) GetMembershipHistory(ctx context.Context, userID string) ([]m.TeamMembership, error) {
+	const method = "TeamService.GetMembershipHistory"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
+	if _, err := service.userService.GetUser(ctx, userID); err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	memberships, err := service.teamRepository.GetMembershipHistory(ctx, userID)
+	if err != nil {
+		slog.Error("failed to get team membership history",
+			"method", method,
+			"user_id", userID,
+			"error", err,
+		)
+		tracing.RecordError(span, err)
+		return nil, errors.WrapInternal(err, "failed to get team membership history")
+	}
+
+	return memberships, nil
+}
+
+// leaveTeam removes the user from the team and ends their membership in its
+// history.
+func (service *teamService) leaveTeam(ctx context.Context, teamName string, userID string) error {
+	const method = "TeamService.leaveTeam"
+
+	if err := service.teamRepository.RemoveMember(ctx, teamName, userID); err != nil {
+		slog.Error("failed to remove team member",
+			"method", method,
+			"team_name", teamName,
+			"user_id", userID,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to remove team member")
+	}
+
+	return service.recordMembershipChange(ctx, teamName, nil, []string{userID})
+}
+
+// recordMembershipChange ends the memberships in the team of the users that
+// left it and starts one for those that joined.
+func (service *teamService) recordMembershipChange(ctx context.Context, teamName string, joined []string, left []string) error {
+	const method = "TeamService.recordMembershipChange"
+
+	if err := service.teamRepository.CloseMemberships(ctx, teamName, left); err != nil {
+		slog.Error("failed to close team memberships",
+			"method", method,
+			"team_name", teamName,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to record team membership change")
+	}
+
+	if err := service.teamRepository.OpenMemberships(ctx, teamName, joined, u.ActorFromContext(ctx)); err != nil {
+		slog.Error("failed to open team memberships",
+			"method", method,
+			"team_name", teamName,
+			"error", err,
+		)
+		return errors.WrapInternal(err, "failed to record team membership change")
+	}
+
+	return nil
+}
+
+func (service *teamService) validateFallbackTeams(ctx context.Context, team *m.Team) error {
+	const method = "TeamService.validateFallbackTeams"
+
+	for _, fallbackName := range team.FallbackTeams {
+		if fallbackName == team.TeamName {
+			return errors.NewValidation("team cannot be its own fallback team")
+		}
+
+		exists, err := service.teamRepository.ExistsByName(ctx, fallbackName)
+		if err != nil {
+			slog.Error("failed to check fallback team existence",
+				"method", method,
+				"team_name", team.TeamName,
+				"fallback_team", fallbackName,
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to check fallback team existence")
+		}
+		if !exists {
+			slog.Warn("fallback team not found",
+				"method", method,
+				"team_name", team.TeamName,
+				"fallback_team", fallbackName,
+			)
+			return errors.NewNotFound(fmt.Sprintf("fallback team %s not found", fallbackName))
+		}
+	}
+
+	return nil
+}
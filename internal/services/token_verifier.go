@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jonx8/pr-review-service/internal/config"
+	m "github.com/jonx8/pr-review-service/internal/models"
+)
+
+// TokenClaims are the claims of a verified user token that the service uses.
+type TokenClaims struct {
+	UserID string
+	Role   m.Role
+}
+
+type TokenVerifier interface {
+	Verify(token string) (*TokenClaims, error)
+}
+
+type jwtVerifier struct {
+	keyfunc   jwt.Keyfunc
+	options   []jwt.ParserOption
+	userClaim string
+	roleClaim string
+}
+
+// NewTokenVerifier loads the JWKS from cfg.JWKSFile or, if it is not set,
+// from cfg.JWKSURL, which is then refreshed in the background until ctx is
+// canceled. It returns nil when neither is configured.
+func NewTokenVerifier(ctx context.Context, cfg config.JWTConfig) (TokenVerifier, error) {
+	var (
+		jwks keyfunc.Keyfunc
+		err  error
+	)
+	switch {
+	case cfg.JWKSFile != "":
+		raw, readErr := os.ReadFile(cfg.JWKSFile)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", readErr)
+		}
+		jwks, err = keyfunc.NewJWKSetJSON(raw)
+	case cfg.JWKSURL != "":
+		jwks, err = keyfunc.NewDefaultCtx(ctx, []string{cfg.JWKSURL})
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &jwtVerifier{
+		keyfunc:   jwks.Keyfunc,
+		options:   options,
+		userClaim: cfg.UserClaim,
+		roleClaim: cfg.RoleClaim,
+	}, nil
+}
+
+func (v *jwtVerifier) Verify(token string) (*TokenClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, v.keyfunc, v.options...); err != nil {
+		return nil, err
+	}
+
+	userID, _ := claims[v.userClaim].(string)
+	if userID == "" {
+		return nil, fmt.Errorf("claim %q is missing", v.userClaim)
+	}
+
+	role := m.RoleMember
+	if value, ok := claims[v.roleClaim]; ok {
+		name, _ := value.(string)
+		role = m.Role(name)
+		if !role.IsValid() {
+			return nil, fmt.Errorf("claim %q has unknown role %v", v.roleClaim, value)
+		}
+	}
+
+	return &TokenClaims{UserID: userID, Role: role}, nil
+}
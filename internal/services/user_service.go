@@ -8,21 +8,27 @@ import (
 	"github.com/jonx8/pr-review-service/internal/errors"
 	m "github.com/jonx8/pr-review-service/internal/models"
 	repo "github.com/jonx8/pr-review-service/internal/repositories"
+	"github.com/jonx8/pr-review-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type UserService interface {
 	GetUser(ctx context.Context, userID string) (*m.User, error)
 	SetIsActive(ctx context.Context, request m.SetActiveRequest) (*m.User, error)
+	SetMaxOpenReviews(ctx context.Context, request m.SetMaxOpenReviewsRequest) (*m.User, error)
+	DeactivateUsers(ctx context.Context, userIDs []string) ([]m.User, error)
 }
 
 type userService struct {
 	userRepository repo.UserRepository
+	publisher      EventPublisher
 	trManager      *manager.Manager
 }
 
-func NewUserService(userRepository repo.UserRepository, trManager *manager.Manager) UserService {
+func NewUserService(userRepository repo.UserRepository, publisher EventPublisher, trManager *manager.Manager) UserService {
 	return &userService{
 		userRepository: userRepository,
+		publisher:      publisher,
 		trManager:      trManager,
 	}
 }
@@ -30,6 +36,9 @@ func NewUserService(userRepository repo.UserRepository, trManager *manager.Manag
 func (service *userService) GetUser(ctx context.Context, userID string) (*m.User, error) {
 	const method = "UserService.GetUser"
 
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", userID))
+	defer span.End()
+
 	user, err := service.userRepository.GetByID(ctx, userID)
 	if err != nil {
 		slog.Error("failed to get user",
@@ -37,6 +46,7 @@ func (service *userService) GetUser(ctx context.Context, userID string) (*m.User
 			"user_id", userID,
 			"error", err,
 		)
+		tracing.RecordError(span, err)
 		return nil, errors.WrapInternal(err, "failed to get user")
 	}
 
@@ -50,19 +60,22 @@ func (service *userService) GetUser(ctx context.Context, userID string) (*m.User
 func (service *userService) SetIsActive(ctx context.Context, request m.SetActiveRequest) (*m.User, error) {
 	const method = "UserService.SetIsActive"
 
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", request.UserID))
+	defer span.End()
+
 	var resultUser *m.User
 	err := service.trManager.Do(ctx, func(ctx context.Context) error {
-		exists, err := service.userRepository.ExistsByID(ctx, request.UserID)
+		currentUser, err := service.userRepository.GetByID(ctx, request.UserID)
 		if err != nil {
-			slog.Error("failed to check user existence",
+			slog.Error("failed to get user",
 				"method", method,
 				"user_id", request.UserID,
 				"error", err,
 			)
-			return errors.WrapInternal(err, "failed to check user existence")
+			return errors.WrapInternal(err, "failed to get user")
 		}
 
-		if !exists {
+		if currentUser == nil {
 			slog.Error("user not found for activation",
 				"method", method,
 				"user_id", request.UserID,
@@ -82,12 +95,77 @@ func (service *userService) SetIsActive(ctx context.Context, request m.SetActive
 		}
 
 		resultUser = user
+		if currentUser.IsActive && !user.IsActive {
+			return service.publisher.Publish(ctx, m.NewEvent(m.EventUserDeactivated, user))
+		}
 		return nil
 	})
 
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
 	return resultUser, nil
 }
+
+// SetMaxOpenReviews changes the user's limit of open reviews. Reviews the user
+// already has are kept even when they exceed the new limit.
+func (service *userService) SetMaxOpenReviews(ctx context.Context, request m.SetMaxOpenReviewsRequest) (*m.User, error) {
+	const method = "UserService.SetMaxOpenReviews"
+
+	ctx, span := tracing.Start(ctx, method, attribute.String("user_id", request.UserID))
+	defer span.End()
+
+	user, err := service.userRepository.SetMaxOpenReviews(ctx, request.UserID, request.MaxOpenReviews)
+	if err != nil {
+		slog.Error("failed to set user max open reviews",
+			"method", method,
+			"user_id", request.UserID,
+			"error", err,
+		)
+		tracing.RecordError(span, err)
+		return nil, errors.WrapInternal(err, "failed to set user max open reviews")
+	}
+
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (service *userService) DeactivateUsers(ctx context.Context, userIDs []string) ([]m.User, error) {
+	const method = "UserService.DeactivateUsers"
+
+	ctx, span := tracing.Start(ctx, method, attribute.Int("users_count", len(userIDs)))
+	defer span.End()
+
+	var deactivated []m.User
+	err := service.trManager.Do(ctx, func(ctx context.Context) error {
+		users, err := service.userRepository.DeactivateMany(ctx, userIDs)
+		if err != nil {
+			slog.Error("failed to deactivate users",
+				"method", method,
+				"users_count", len(userIDs),
+				"error", err,
+			)
+			return errors.WrapInternal(err, "failed to deactivate users")
+		}
+
+		events := make([]m.Event, len(users))
+		for i := range users {
+			events[i] = m.NewEvent(m.EventUserDeactivated, &users[i])
+		}
+
+		deactivated = users
+		return service.publisher.Publish(ctx, events...)
+	})
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	return deactivated, nil
+}
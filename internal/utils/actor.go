@@ -0,0 +1,18 @@
+package utils
+
+import "context"
+
+type actorKey struct{}
+
+// WithActor stores the id of the user on whose behalf the request is made.
+func WithActor(ctx context.Context, actorID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actorID)
+}
+
+func ActorFromContext(ctx context.Context) *string {
+	actorID, ok := ctx.Value(actorKey{}).(string)
+	if !ok || actorID == "" {
+		return nil
+	}
+	return &actorID
+}
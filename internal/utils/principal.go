@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"context"
+
+	m "github.com/jonx8/pr-review-service/internal/models"
+)
+
+type principalKey struct{}
+
+// WithPrincipal stores the authenticated caller of the request.
+func WithPrincipal(ctx context.Context, principal *m.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) *m.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*m.Principal)
+	return principal
+}
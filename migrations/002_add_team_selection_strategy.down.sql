@@ -0,0 +1 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS selection_strategy;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE teams
+    ADD COLUMN IF NOT EXISTS selection_strategy VARCHAR(20) NOT NULL DEFAULT 'LEAST_LOADED'
+        CHECK (selection_strategy IN ('RANDOM', 'ROUND_ROBIN', 'LEAST_LOADED', 'WEIGHTED'));
@@ -0,0 +1 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS reviewers_required;
\ No newline at end of file
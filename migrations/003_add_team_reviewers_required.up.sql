@@ -0,0 +1,3 @@
+ALTER TABLE teams
+    ADD COLUMN IF NOT EXISTS reviewers_required INTEGER NOT NULL DEFAULT 2
+        CHECK (reviewers_required > 0);
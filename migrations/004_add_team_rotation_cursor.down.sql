@@ -0,0 +1 @@
+ALTER TABLE teams DROP COLUMN IF EXISTS rotation_cursor;
\ No newline at end of file
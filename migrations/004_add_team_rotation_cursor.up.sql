@@ -0,0 +1,2 @@
+ALTER TABLE teams
+    ADD COLUMN IF NOT EXISTS rotation_cursor VARCHAR(50) DEFAULT NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS team_fallbacks (
+    team_name VARCHAR(100) NOT NULL REFERENCES teams(name) ON DELETE CASCADE,
+    fallback_team_name VARCHAR(100) NOT NULL REFERENCES teams(name) ON DELETE CASCADE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (team_name, fallback_team_name),
+    CHECK (team_name <> fallback_team_name)
+);
+
+ALTER TABLE pr_reviewers
+    ADD COLUMN IF NOT EXISTS fallback_team VARCHAR(100) DEFAULT NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pr_reviews;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS pr_reviews (
+    id BIGSERIAL PRIMARY KEY,
+    pr_id VARCHAR(50) NOT NULL REFERENCES pull_requests(id) ON DELETE CASCADE,
+    reviewer_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    state VARCHAR(20) NOT NULL CHECK (state IN ('APPROVED', 'CHANGES_REQUESTED', 'COMMENTED')),
+    comment TEXT DEFAULT NULL,
+    submitted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_reviews_pr_reviewer ON pr_reviews (pr_id, reviewer_id, submitted_at DESC);
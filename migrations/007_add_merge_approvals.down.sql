@@ -0,0 +1,2 @@
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS merge_forced;
+ALTER TABLE teams DROP COLUMN IF EXISTS approvals_required;
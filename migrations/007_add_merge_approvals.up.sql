@@ -0,0 +1,6 @@
+ALTER TABLE teams
+    ADD COLUMN IF NOT EXISTS approvals_required INTEGER NOT NULL DEFAULT 1
+        CHECK (approvals_required >= 0);
+
+ALTER TABLE pull_requests
+    ADD COLUMN IF NOT EXISTS merge_forced BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,16 @@
+-- Before this migration a PR could only be open or merged. Drafts and reopened
+-- PRs are open, but a closed PR would come back as open, so refuse instead.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pull_requests WHERE status = 'CLOSED') THEN
+        RAISE EXCEPTION 'cannot roll back PR lifecycle: closed pull requests would be reopened';
+    END IF;
+END
+$$;
+
+UPDATE pull_requests SET status = 'OPEN' WHERE status IN ('DRAFT', 'REOPENED', 'CLOSED');
+
+ALTER TABLE pull_requests
+    DROP CONSTRAINT IF EXISTS pull_requests_status_check,
+    ALTER COLUMN status DROP NOT NULL,
+    DROP COLUMN IF EXISTS closed_at;
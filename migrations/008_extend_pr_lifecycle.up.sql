@@ -0,0 +1,9 @@
+ALTER TABLE pull_requests
+    ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+UPDATE pull_requests SET status = 'OPEN' WHERE status IS NULL;
+
+ALTER TABLE pull_requests
+    ALTER COLUMN status SET NOT NULL,
+    ADD CONSTRAINT pull_requests_status_check
+        CHECK (status IN ('DRAFT', 'OPEN', 'REOPENED', 'MERGED', 'CLOSED'));
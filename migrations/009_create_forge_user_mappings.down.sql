@@ -0,0 +1 @@
+DROP TABLE IF EXISTS forge_user_mappings;
\ No newline at end of file
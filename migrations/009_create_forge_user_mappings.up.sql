@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS forge_user_mappings (
+    forge VARCHAR(20) NOT NULL,
+    login VARCHAR(100) NOT NULL,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (forge, login)
+);
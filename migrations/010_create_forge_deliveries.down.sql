@@ -0,0 +1 @@
+DROP TABLE IF EXISTS forge_deliveries;
\ No newline at end of file
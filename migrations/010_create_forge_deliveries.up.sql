@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS forge_deliveries (
+    forge VARCHAR(20) NOT NULL,
+    delivery_id VARCHAR(100) NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (forge, delivery_id)
+);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS pr_events (
+    id BIGSERIAL PRIMARY KEY,
+    pr_id VARCHAR(50) NOT NULL REFERENCES pull_requests(id),
+    event_type VARCHAR(20) NOT NULL CHECK (event_type IN ('ASSIGNED', 'REPLACED')),
+    reviewer_id VARCHAR(50) NOT NULL,
+    previous_reviewer_id VARCHAR(50) DEFAULT NULL,
+    fallback_team VARCHAR(100) DEFAULT NULL,
+    actor_id VARCHAR(50) DEFAULT NULL,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('AUTO', 'MANUAL', 'DEACTIVATION')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_events_pr ON pr_events (pr_id, created_at, id);
+
+CREATE OR REPLACE FUNCTION pr_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'pr_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER pr_events_append_only
+    BEFORE UPDATE OR DELETE ON pr_events
+    FOR EACH ROW EXECUTE FUNCTION pr_events_append_only();
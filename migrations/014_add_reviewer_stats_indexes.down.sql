@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_pr_events_previous_reviewer;
+DROP INDEX IF EXISTS idx_pr_reviewers_user;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user ON pr_reviewers (user_id, assigned_at);
+CREATE INDEX IF NOT EXISTS idx_pr_events_previous_reviewer ON pr_events (previous_reviewer_id, created_at) WHERE event_type = 'REPLACED';
@@ -0,0 +1,25 @@
+-- pr_events is append-only, so REMOVAL events cannot be removed to satisfy
+-- the restored check, and users without a team would break the NOT NULL
+-- constraint. Refuse instead.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pr_events WHERE reason = 'REMOVAL') THEN
+        RAISE EXCEPTION 'cannot roll back team membership management: pr_events has REMOVAL events';
+    END IF;
+    IF EXISTS (SELECT 1 FROM users WHERE team_name IS NULL) THEN
+        RAISE EXCEPTION 'cannot roll back team membership management: some users have no team';
+    END IF;
+END
+$$;
+
+ALTER TABLE pr_events DROP CONSTRAINT IF EXISTS pr_events_reason_check;
+ALTER TABLE pr_events
+    ADD CONSTRAINT pr_events_reason_check
+        CHECK (reason IN ('AUTO', 'MANUAL', 'DEACTIVATION'));
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;
+ALTER TABLE users
+    ADD CONSTRAINT users_team_name_fkey
+        FOREIGN KEY (team_name) REFERENCES teams(name) ON DELETE CASCADE;
+
+ALTER TABLE users ALTER COLUMN team_name SET NOT NULL;
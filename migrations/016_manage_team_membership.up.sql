@@ -0,0 +1,11 @@
+ALTER TABLE users ALTER COLUMN team_name DROP NOT NULL;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;
+ALTER TABLE users
+    ADD CONSTRAINT users_team_name_fkey
+        FOREIGN KEY (team_name) REFERENCES teams(name) ON DELETE RESTRICT;
+
+ALTER TABLE pr_events DROP CONSTRAINT IF EXISTS pr_events_reason_check;
+ALTER TABLE pr_events
+    ADD CONSTRAINT pr_events_reason_check
+        CHECK (reason IN ('AUTO', 'MANUAL', 'DEACTIVATION', 'REMOVAL'));
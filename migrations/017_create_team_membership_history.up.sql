@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS team_membership_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id),
+    team_name VARCHAR(100) NOT NULL,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    left_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    actor_id VARCHAR(50) DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_membership_history_user ON team_membership_history (user_id, joined_at, id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_membership_history_current ON team_membership_history (user_id) WHERE left_at IS NULL;
+
+-- Current memberships predate the history, so they start when it was created.
+INSERT INTO team_membership_history (user_id, team_name)
+SELECT id, team_name FROM users WHERE team_name IS NOT NULL;
@@ -0,0 +1,35 @@
+-- users.team_name holds a single team, so rolling back while someone belongs
+-- to several teams would drop memberships. Refuse instead of losing them.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM team_memberships
+        GROUP BY user_id
+        HAVING COUNT(*) > 1
+    ) THEN
+        RAISE EXCEPTION 'cannot roll back team memberships: some users belong to more than one team';
+    END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS idx_team_membership_history_current;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_membership_history_current
+    ON team_membership_history (user_id) WHERE left_at IS NULL;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS team_name VARCHAR(100) DEFAULT NULL REFERENCES teams(name) ON DELETE RESTRICT;
+
+UPDATE users u
+SET team_name = (
+    SELECT tm.team_name
+    FROM team_memberships tm
+    WHERE tm.user_id = u.id
+    ORDER BY tm.joined_at, tm.team_name
+    LIMIT 1
+);
+
+DROP INDEX IF EXISTS idx_pull_requests_team_status;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS team_name;
+
+DROP TABLE IF EXISTS team_memberships;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS team_memberships (
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id),
+    team_name VARCHAR(100) NOT NULL REFERENCES teams(name) ON DELETE RESTRICT,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, team_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_memberships_team ON team_memberships (team_name);
+
+INSERT INTO team_memberships (user_id, team_name, joined_at)
+SELECT u.id, u.team_name, COALESCE(h.joined_at, CURRENT_TIMESTAMP)
+FROM users u
+LEFT JOIN team_membership_history h ON h.user_id = u.id AND h.left_at IS NULL
+WHERE u.team_name IS NOT NULL;
+
+ALTER TABLE pull_requests
+    ADD COLUMN IF NOT EXISTS team_name VARCHAR(100) DEFAULT NULL REFERENCES teams(name) ON DELETE SET NULL;
+
+UPDATE pull_requests pr
+SET team_name = u.team_name
+FROM users u
+WHERE u.id = pr.author_id;
+
+CREATE INDEX IF NOT EXISTS idx_pull_requests_team_status ON pull_requests (team_name, status);
+
+ALTER TABLE users DROP COLUMN IF EXISTS team_name;
+
+DROP INDEX IF EXISTS idx_team_membership_history_current;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_membership_history_current
+    ON team_membership_history (user_id, team_name) WHERE left_at IS NULL;
@@ -0,0 +1,16 @@
+-- pr_events is append-only, so ABSENCE events cannot be removed to satisfy
+-- the restored check. Refuse instead.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pr_events WHERE reason = 'ABSENCE') THEN
+        RAISE EXCEPTION 'cannot roll back user absences: pr_events has ABSENCE events';
+    END IF;
+END
+$$;
+
+ALTER TABLE pr_events DROP CONSTRAINT IF EXISTS pr_events_reason_check;
+ALTER TABLE pr_events
+    ADD CONSTRAINT pr_events_reason_check
+        CHECK (reason IN ('AUTO', 'MANUAL', 'DEACTIVATION', 'REMOVAL'));
+
+DROP TABLE IF EXISTS user_absences;
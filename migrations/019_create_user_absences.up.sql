@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS user_absences (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL REFERENCES users(id),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason VARCHAR(255) DEFAULT NULL,
+    reassign_reviews BOOLEAN NOT NULL DEFAULT FALSE,
+    reviews_reassigned_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    external_uid VARCHAR(255) DEFAULT NULL,
+    actor_id VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT user_absences_period_check CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_absences_user ON user_absences (user_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_user_absences_pending
+    ON user_absences (starts_at) WHERE reassign_reviews AND reviews_reassigned_at IS NULL;
+-- Re-importing a calendar updates the absences it created instead of duplicating them.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_absences_external
+    ON user_absences (user_id, external_uid) WHERE external_uid IS NOT NULL;
+
+ALTER TABLE pr_events DROP CONSTRAINT IF EXISTS pr_events_reason_check;
+ALTER TABLE pr_events
+    ADD CONSTRAINT pr_events_reason_check
+        CHECK (reason IN ('AUTO', 'MANUAL', 'DEACTIVATION', 'REMOVAL', 'ABSENCE'));
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS review_queue;
+
+ALTER TABLE teams
+    DROP COLUMN IF EXISTS capacity_policy,
+    DROP COLUMN IF EXISTS max_open_reviews;
+
+ALTER TABLE users DROP COLUMN IF EXISTS max_open_reviews;
@@ -0,0 +1,24 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS max_open_reviews INTEGER DEFAULT NULL
+        CHECK (max_open_reviews > 0);
+
+ALTER TABLE teams
+    ADD COLUMN IF NOT EXISTS max_open_reviews INTEGER DEFAULT NULL
+        CHECK (max_open_reviews > 0),
+    ADD COLUMN IF NOT EXISTS capacity_policy VARCHAR(20) NOT NULL DEFAULT 'OVER_ASSIGN'
+        CHECK (capacity_policy IN ('OVER_ASSIGN', 'LEAVE_UNFILLED', 'FAIL'));
+
+-- Review slots left unfilled because every candidate was at capacity. A slot
+-- with replaces_reviewer_id set hands that reviewer's review over once filled.
+CREATE TABLE IF NOT EXISTS review_queue (
+    id BIGSERIAL PRIMARY KEY,
+    pr_id VARCHAR(50) NOT NULL REFERENCES pull_requests(id) ON DELETE CASCADE,
+    replaces_reviewer_id VARCHAR(50) DEFAULT NULL REFERENCES users(id),
+    reason VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_queue_pr ON review_queue (pr_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_review_queue_replacement
+    ON review_queue (pr_id, replaces_reviewer_id) WHERE replaces_reviewer_id IS NOT NULL;
@@ -0,0 +1,20 @@
+-- pr_events is append-only, so forced merge events, which have no reviewer,
+-- cannot be removed to satisfy the restored constraints. Refuse instead.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM pr_events
+        WHERE event_type NOT IN ('ASSIGNED', 'REPLACED') OR reviewer_id IS NULL
+    ) THEN
+        RAISE EXCEPTION 'cannot roll back forced merge events: pr_events has events without a reviewer';
+    END IF;
+END
+$$;
+
+ALTER TABLE pr_events DROP CONSTRAINT IF EXISTS pr_events_event_type_check;
+ALTER TABLE pr_events
+    ADD CONSTRAINT pr_events_event_type_check
+        CHECK (event_type IN ('ASSIGNED', 'REPLACED'));
+
+ALTER TABLE pr_events ALTER COLUMN reviewer_id SET NOT NULL;
@@ -0,0 +1,8 @@
+-- Changes of the PR itself, such as a forced merge, are recorded without a
+-- reviewer.
+ALTER TABLE pr_events ALTER COLUMN reviewer_id DROP NOT NULL;
+
+ALTER TABLE pr_events DROP CONSTRAINT IF EXISTS pr_events_event_type_check;
+ALTER TABLE pr_events
+    ADD CONSTRAINT pr_events_event_type_check
+        CHECK (event_type IN ('ASSIGNED', 'REPLACED', 'MERGE_FORCED'));
@@ -0,0 +1,14 @@
+-- pr_events is append-only, so rows with the new event types cannot be
+-- removed to satisfy the restored check. Refuse instead.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pr_events WHERE event_type IN ('CLOSED', 'REOPENED')) THEN
+        RAISE EXCEPTION 'cannot roll back PR status events: pr_events has CLOSED or REOPENED events';
+    END IF;
+END
+$$;
+
+ALTER TABLE pr_events DROP CONSTRAINT IF EXISTS pr_events_event_type_check;
+ALTER TABLE pr_events
+    ADD CONSTRAINT pr_events_event_type_check
+        CHECK (event_type IN ('ASSIGNED', 'REPLACED', 'MERGE_FORCED'));
@@ -0,0 +1,4 @@
+ALTER TABLE pr_events DROP CONSTRAINT IF EXISTS pr_events_event_type_check;
+ALTER TABLE pr_events
+    ADD CONSTRAINT pr_events_event_type_check
+        CHECK (event_type IN ('ASSIGNED', 'REPLACED', 'MERGE_FORCED', 'CLOSED', 'REOPENED'));
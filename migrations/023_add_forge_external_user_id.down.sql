@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_forge_user_mappings_external_user;
+
+ALTER TABLE forge_user_mappings DROP COLUMN IF EXISTS external_user_id;
@@ -0,0 +1,5 @@
+-- GitLab merge request events identify the author only by the numeric user id.
+ALTER TABLE forge_user_mappings ADD COLUMN IF NOT EXISTS external_user_id BIGINT DEFAULT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_forge_user_mappings_external_user
+    ON forge_user_mappings (forge, external_user_id) WHERE external_user_id IS NOT NULL;
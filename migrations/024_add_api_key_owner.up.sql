@@ -0,0 +1,3 @@
+-- Changes made with an API key are attributed to the user who owns it.
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS owner_id VARCHAR(50) DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL;